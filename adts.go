@@ -0,0 +1,169 @@
+package gemist
+
+// MPEG-TS packet size and the stream types of its program map table.
+const (
+	tsPacketSize = 188
+	tsStreamAAC  = 0x0f // AAC audio in ADTS
+)
+
+// tsVideoStreams are the stream types of video in a program map table.
+var tsVideoStreams = map[byte]bool{
+	0x01: true, // MPEG-1
+	0x02: true, // MPEG-2
+	0x10: true, // MPEG-4 part 2
+	0x1b: true, // H.264
+	0x24: true, // H.265
+}
+
+// segmentADTS returns the audio of an HLS segment as ADTS if the segment only
+// carries AAC audio, either in MPEG-TS or as packed audio behind an ID3 tag.
+// It reports false for segments with video and for other formats.
+func segmentADTS(data []byte) ([]byte, bool) {
+	if len(data) > 0 && data[0] == 0x47 {
+		return tsADTS(data)
+	}
+
+	for len(data) >= 10 && string(data[:3]) == "ID3" {
+		n := 10 + (int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9]))
+		if data[5]&0x10 != 0 {
+			n += 10 // footer
+		}
+		if n > len(data) {
+			return nil, false
+		}
+		data = data[n:]
+		if isADTS(data) {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// tsADTS returns the AAC stream of MPEG-TS data, which is ADTS already, if the
+// program has no video. Only the first program and its first AAC stream are
+// used.
+func tsADTS(data []byte) ([]byte, bool) {
+	if len(data)%tsPacketSize != 0 {
+		return nil, false
+	}
+
+	var (
+		pmtPID = -1
+		aacPID = -1
+		out    []byte
+	)
+	for off := 0; off < len(data); off += tsPacketSize {
+		pkt := data[off : off+tsPacketSize]
+		if pkt[0] != 0x47 {
+			return nil, false
+		}
+		start := pkt[1]&0x40 != 0
+		pid := int(pkt[1]&0x1f)<<8 | int(pkt[2])
+
+		payload := pkt[4:]
+		switch pkt[3] >> 4 & 3 {
+		case 1: // payload only
+		case 3: // adaptation field and payload
+			n := 1 + int(pkt[4])
+			if n > len(payload) {
+				return nil, false
+			}
+			payload = payload[n:]
+		default:
+			continue
+		}
+
+		switch {
+		case pid == 0 && start && pmtPID < 0:
+			if pmtPID = parsePAT(payload); pmtPID < 0 {
+				return nil, false
+			}
+		case pid == pmtPID && start && aacPID < 0:
+			var video bool
+			if aacPID, video = parsePMT(payload); video || aacPID < 0 {
+				return nil, false
+			}
+		case pid == aacPID:
+			if start {
+				if payload = pesPayload(payload); payload == nil {
+					return nil, false
+				}
+			}
+			out = append(out, payload...)
+		}
+	}
+
+	if !isADTS(out) {
+		return nil, false
+	}
+	return out, true
+}
+
+// psiSection returns the table section that starts in payload, without its
+// CRC, or nil if it is truncated.
+func psiSection(payload []byte) []byte {
+	if len(payload) < 1 || 1+int(payload[0])+3 > len(payload) {
+		return nil
+	}
+	sec := payload[1+int(payload[0]):]
+	n := 3 + (int(sec[1]&0x0f)<<8 | int(sec[2]))
+	if n > len(sec) || n < 12 {
+		return nil
+	}
+	return sec[:n-4]
+}
+
+// parsePAT returns the PID of the program map table of the first program in
+// a program association table, or -1 if there is none.
+func parsePAT(payload []byte) int {
+	sec := psiSection(payload)
+	if sec == nil || sec[0] != 0x00 {
+		return -1
+	}
+	for i := 8; i+4 <= len(sec); i += 4 {
+		if sec[i] != 0 || sec[i+1] != 0 { // not the network PID
+			return int(sec[i+2]&0x1f)<<8 | int(sec[i+3])
+		}
+	}
+	return -1
+}
+
+// parsePMT returns the PID of the first AAC stream in a program map table, or
+// -1 if there is none, and reports whether the program has video.
+func parsePMT(payload []byte) (aac int, video bool) {
+	aac = -1
+	sec := psiSection(payload)
+	if sec == nil || sec[0] != 0x02 {
+		return
+	}
+	i := 12 + (int(sec[10]&0x0f)<<8 | int(sec[11]))
+	for i+5 <= len(sec) {
+		typ, pid := sec[i], int(sec[i+1]&0x1f)<<8|int(sec[i+2])
+		switch {
+		case tsVideoStreams[typ]:
+			video = true
+		case typ == tsStreamAAC && aac < 0:
+			aac = pid
+		}
+		i += 5 + (int(sec[i+3]&0x0f)<<8 | int(sec[i+4]))
+	}
+	return
+}
+
+// pesPayload returns the data of the PES packet that starts in payload, or nil
+// if it is not one.
+func pesPayload(payload []byte) []byte {
+	if len(payload) < 9 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+		return nil
+	}
+	n := 9 + int(payload[8])
+	if n > len(payload) {
+		return nil
+	}
+	return payload[n:]
+}
+
+// isADTS reports whether b starts with an ADTS frame header.
+func isADTS(b []byte) bool {
+	return len(b) >= 7 && b[0] == 0xff && b[1]&0xf6 == 0xf0
+}
@@ -0,0 +1,87 @@
+package gemist
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testADTSFrame returns an ADTS frame of AAC audio with n bytes of data.
+func testADTSFrame(n int, b byte) []byte {
+	return append([]byte{0xff, 0xf1, 0x50, 0x80, 0x2e, 0x7f, 0xfc}, bytes.Repeat([]byte{b}, n)...)
+}
+
+// tsPacket returns an MPEG-TS packet of pid with payload, padded with an
+// adaptation field.
+func tsPacket(pid int, start bool, payload []byte) []byte {
+	pkt := []byte{0x47, byte(pid >> 8 & 0x1f), byte(pid), 0x10}
+	if start {
+		pkt[1] |= 0x40
+	}
+	if n := 184 - len(payload); n > 0 {
+		pkt[3] = 0x30
+		pkt = append(pkt, byte(n-1))
+		if n > 1 {
+			pkt = append(pkt, 0x00)
+			pkt = append(pkt, bytes.Repeat([]byte{0xff}, n-2)...)
+		}
+	}
+	return append(pkt, payload...)
+}
+
+// tsSection returns the payload of a packet that starts table section tid
+// with data, followed by a zero CRC.
+func tsSection(tid byte, data []byte) []byte {
+	n := 5 + len(data) + 4
+	sec := []byte{0x00, tid, 0xb0 | byte(n>>8), byte(n), 0x00, 0x01, 0xc1, 0x00, 0x00}
+	sec = append(sec, data...)
+	return append(sec, 0, 0, 0, 0)
+}
+
+// testTSSegment returns an MPEG-TS segment of a program with streams of the
+// given types, the first of which carries audio as PID 0x101.
+func testTSSegment(audio []byte, types ...byte) []byte {
+	seg := tsPacket(0, true, tsSection(0x00, []byte{0x00, 0x01, 0xf0, 0x00}))
+
+	pmt := []byte{0xe1, 0x00, 0xf0, 0x00} // PCR PID, no program info
+	for i, typ := range types {
+		pmt = append(pmt, typ, 0xe1, byte(0x01+i), 0xf0, 0x00)
+	}
+	seg = append(seg, tsPacket(0x1000, true, tsSection(0x02, pmt))...)
+
+	pes := append([]byte{0x00, 0x00, 0x01, 0xc0, 0x00, 0x00, 0x80, 0x80, 0x05, 0x21, 0x00, 0x01, 0x00, 0x01}, audio...)
+	for start := true; len(pes) > 0; start = false {
+		n := min(len(pes), 184)
+		seg = append(seg, tsPacket(0x101, start, pes[:n])...)
+		pes = pes[n:]
+	}
+	return seg
+}
+
+func TestSegmentADTS(t *testing.T) {
+	assert := assert.New(t)
+
+	audio := append(testADTSFrame(300, 'a'), testADTSFrame(120, 'b')...)
+
+	adts, ok := segmentADTS(testTSSegment(audio, tsStreamAAC, 0x15))
+	if assert.True(ok, "audio-only segment not converted") {
+		assert.Equal(audio, adts)
+	}
+
+	_, ok = segmentADTS(testTSSegment(audio, tsStreamAAC, 0x1b))
+	assert.False(ok, "segment with video converted")
+
+	_, ok = segmentADTS(testTSSegment(bytes.Repeat([]byte{'m'}, 200), 0x03))
+	assert.False(ok, "MP3 segment converted")
+
+	// Packed audio starts with an ID3 tag of its timestamp.
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), "PRIV\x00"...)
+	adts, ok = segmentADTS(append(id3, audio...))
+	if assert.True(ok, "packed audio not converted") {
+		assert.Equal(audio, adts)
+	}
+
+	_, ok = segmentADTS(testHLSSegment(1))
+	assert.False(ok, "invalid segment converted")
+}
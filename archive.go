@@ -0,0 +1,128 @@
+package gemist
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gopkg.in/xmlpath.v2"
+)
+
+// ArchiveProgress reports the progress of getting a program archive.
+type ArchiveProgress struct {
+	Pages int // number of archive pages retrieved
+	Items int // number of items on the retrieved pages
+	Total int // number of items in the archive
+}
+
+// GetBroadcasts gets all broadcasts of the program at url by following its
+// archive pages until the number of broadcasts reported by the program is
+// collected. Broadcasts are returned in archive order, newest first.
+//
+// If progress is not nil, it is called after each archive page. If ctx is
+// done before all broadcasts are collected, the broadcasts collected so far
+// are returned with the error of ctx.
+func (c *Client) GetBroadcasts(ctx context.Context, url string, progress func(ArchiveProgress)) ([]*BroadcastProxy, error) {
+	var (
+		bs   []*BroadcastProxy
+		seen = make(map[string]bool)
+	)
+
+	parse := func(n *xmlpath.Node, base string, now time.Time) (int, int, error) {
+		page, total, err := parseProgramBroadcasts(n, base, now)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// Items can shift between pages when new broadcasts are added.
+		for _, bp := range page {
+			if !seen[bp.URL] {
+				seen[bp.URL] = true
+				bs = append(bs, bp)
+			}
+		}
+		return len(page), total, nil
+	}
+
+	err := c.getArchive(ctx, url, "broadcast", parse, progress)
+	return bs, err
+}
+
+// An archiveParser parses an archive page, collects its items and returns the
+// number of items on the page and the total number of items in the archive.
+// now is the time the page was served.
+type archiveParser func(node *xmlpath.Node, base string, now time.Time) (n int, total int, err error)
+
+// getArchive requests the archive pages of media type typ of the program at
+// rawurl until total items are parsed or a page has no items.
+func (c *Client) getArchive(ctx context.Context, rawurl, typ string, parse archiveParser, progress func(ArchiveProgress)) error {
+	var pr ArchiveProgress
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		u, err := archiveURL(mediaPath(rawurl), typ, pr.Items)
+		if err != nil {
+			return err
+		}
+
+		n, total, err := c.getPage(ctx, u, parse)
+		if err != nil {
+			return err
+		}
+
+		pr.Pages++
+		pr.Items += n
+		pr.Total = total
+		if progress != nil {
+			progress(pr)
+		}
+
+		if n == 0 || pr.Items >= total {
+			return nil
+		}
+	}
+}
+
+func (c *Client) getPage(ctx context.Context, url string, parse archiveParser) (int, int, error) {
+	r, err := c.get(ctx, url)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Body.Close()
+
+	n, total, err := parseArchivePage(r.Body, c.baseURL(), responseDate(r), parse)
+	if err != nil {
+		return 0, 0, withURL(err, r.Request.URL.String())
+	}
+
+	return n, total, nil
+}
+
+func parseArchivePage(r io.Reader, base string, now time.Time, parse archiveParser) (int, int, error) {
+	n, err := xmlpath.ParseHTML(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return parse(n, base, now)
+}
+
+// archiveURL returns the URL of the archive page of media type typ of the
+// program at rawurl, starting at item start.
+func archiveURL(rawurl, typ string, start int) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("media_type", typ)
+	q.Set("start", strconv.Itoa(start))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,114 @@
+package gemist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// archiveServer serves an archive of total items, rows items per page.
+func archiveServer(t *testing.T, typ string, total, rows int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("media_type"); got != typ {
+			t.Errorf("media type %q requested, want %q", got, typ)
+		}
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		fmt.Fprint(w, testArchivePage(typ, start, rows, total))
+	}))
+}
+
+func testArchivePage(typ string, start, rows, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head></head><body><div class='%[1]s-block video-container-block' data-media-type='%[1]s' id='%[1]ss-block'>", typ)
+	fmt.Fprintf(&b, "<span class='filter-info'></span><div class='content'><div class='search-results' data-num-found='%d' data-rows='%d' data-start='%d'>", total, rows, start)
+	for i := start; i < start+rows && i < total; i++ {
+		id := fmt.Sprintf("POMS_VPRO_%d", 396279-i)
+		if typ == "segment" {
+			id = fmt.Sprintf("POMS_VPRO_%d/POMS_VPRO_%d", 396637-2*i, 396638-2*i)
+		}
+		fmt.Fprintf(&b, `<div class='list-item non-responsive row-fluid'>
+<div class='span4'><div class='image-container'>
+<a href="/radio-bergeijk/06-10-2007/%[1]s"><img src="http://images.poms.omroep.nl/image/s174/c174x98/215303.png" />
+<div class="overlay-icon"><span class="npo-glyph speaker"></span> 25:02</div>
+</a></div></div>
+<div class='span8'>
+<a href="/radio-bergeijk/06-10-2007/%[1]s"><h4>
+Radio Bergeijk %[2]d
+</h4>
+<h5>Elke zaterdagavond om Half 7 op Radio 1 · Za 6 okt 2007 18:32 · 25 min</h5>
+<p>Aflevering %[2]d</p>
+</a></div>
+</div>
+`, id, i)
+	}
+	b.WriteString("</div></div></div></body></html>")
+	return b.String()
+}
+
+func TestClient_GetBroadcasts(t *testing.T) {
+	assert := assert.New(t)
+
+	s := archiveServer(t, "broadcast", 20, 8)
+	defer s.Close()
+
+	var prs []ArchiveProgress
+	c := Client{BaseURL: s.URL}
+	bs, err := c.GetBroadcasts(context.Background(), "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280", func(pr ArchiveProgress) {
+		prs = append(prs, pr)
+	})
+	assert.NoError(err)
+	assert.Len(bs, 20, "number of broadcasts not equal")
+	assert.Equal([]ArchiveProgress{
+		{Pages: 1, Items: 8, Total: 20},
+		{Pages: 2, Items: 16, Total: 20},
+		{Pages: 3, Items: 20, Total: 20},
+	}, prs, "progress not equal")
+}
+
+func TestClient_GetBroadcasts_canceled(t *testing.T) {
+	assert := assert.New(t)
+
+	s := archiveServer(t, "broadcast", 20, 8)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := Client{BaseURL: s.URL}
+	bs, err := c.GetBroadcasts(ctx, "/radio-bergeijk/POMS_S_VPRO_396280", func(pr ArchiveProgress) {
+		cancel()
+	})
+	assert.ErrorIs(err, context.Canceled)
+	assert.Len(bs, 8, "broadcasts collected before cancellation not returned")
+}
+
+func TestArchiveURL(t *testing.T) {
+	u, err := archiveURL("http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280", "broadcast", 16)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280?media_type=broadcast&start=16", u)
+	}
+}
+
+func TestClient_GetSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	s := archiveServer(t, "segment", 10, 8)
+	defer s.Close()
+
+	c := Client{BaseURL: s.URL}
+	ss, err := c.GetSegments(context.Background(), "/radio-bergeijk/POMS_S_VPRO_396280", nil)
+	assert.NoError(err)
+	if assert.Len(ss, 10, "number of segments not equal") {
+		assert.Equal(MediaID("POMS_VPRO_396637"), ss[0].ParentID, "parent ID not equal")
+		assert.Equal(s.URL+"/radio-bergeijk/06-10-2007/POMS_VPRO_396637", ss[0].ParentURL, "parent URL not equal")
+		assert.Equal(25*time.Minute+2*time.Second, ss[0].Length, "length not equal")
+	}
+}
@@ -2,13 +2,10 @@
 package gemist
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"io"
-	"net/http"
-	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"gopkg.in/xmlpath.v2"
@@ -22,45 +19,75 @@ type Broadcast struct {
 	Length          time.Duration
 	Type            BroadcastType
 	MediaURL        string
+	Segments        []*Segment
+
+	// Program refers to the program the broadcast belongs to, or is nil if
+	// the page does not link to it. Use FetchProgram to get it.
+	Program *ProgramRef
+
+	// Schema is the schema.org metadata of the broadcast page, or nil if it
+	// has none.
+	Schema *Schema
+
+	// Streams contains the stream locations of the broadcast, after they
+	// are resolved with ResolveStreams.
+	Streams []Stream
+}
+
+// ProgramRef refers to the program of a broadcast, as linked from the
+// broadcast page.
+type ProgramRef struct {
+	ID    MediaID
+	Title string
+	URL   string
 }
 
-// BroadcastType indicates the type of media (audio or video).
+// BroadcastType indicates the type of media (audio or video) and whether it
+// is a regular broadcast, a segment of one or a live stream.
 type BroadcastType int
 
 // Broadcast media types.
 const (
 	Video BroadcastType = iota
 	Audio
+	VideoSegment
+	AudioSegment
+	LiveVideo
+	LiveAudio
 )
 
 // GetBroadcast gets the page content from url, parses it and returns a Broadcast.
+// It uses DefaultClient.
 func GetBroadcast(url string) (*Broadcast, error) {
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-
-	return ParseBroadcast(r.Body)
+	return DefaultClient.GetBroadcast(context.Background(), url)
 }
 
 var (
-	pBType      = xmlpath.MustCompile("/html/head/meta[@name='og:type']/@content")
-	pBLDesc     = xmlpath.MustCompile("//*/div[@class='content']/p/span[3]/text()")
-	pBLDescNPO3 = xmlpath.MustCompile("//div[contains(@class,'meta-content')]/div[1]/p[1]/span[3]/text()")
-	pBDate      = xmlpath.MustCompile("//span[@itemprop='startDate']/text()")
+	pBType      = mustCompile("/html/head/meta[@name='og:type']/@content")
+	pBLDesc     = mustCompile("//*/div[@class='content']/p/span[3]/text()")
+	pBLDescNPO3 = mustCompile("//div[contains(@class,'meta-content')]/div[1]/p[1]/span[3]/text()")
+	pBDate      = mustCompile("//span[@itemprop='startDate']/text()")
 )
 
 const broadcastDateLayout = "2006-01-02 15:04:05 -0700"
 
-// ParseBroadcast parses content of a reader into a Broadcast.
+// ParseBroadcast parses content of a reader into a Broadcast. Relative segment
+// URLs are resolved against DefaultBaseURL.
 func ParseBroadcast(r io.Reader) (*Broadcast, error) {
+	return parseBroadcast(r, DefaultBaseURL)
+}
+
+func parseBroadcast(r io.Reader, base string) (*Broadcast, error) {
 	n, err := xmlpath.ParseHTML(r)
 	if err != nil {
 		return nil, err
 	}
 
+	schema := parseSchema(n, base)
+	episode := schema.items(schemaEpisodeTypes...)
+
 	// -- MediaItem --
-	mi, err := parseMediaItem(n)
+	mi, err := parseMediaItem(n, episode)
 	if err != nil {
 		return nil, err
 	}
@@ -72,53 +99,80 @@ func ParseBroadcast(r io.Reader) (*Broadcast, error) {
 		longDesc, ok = pBLDescNPO3.String(n)
 	}
 	if !ok {
-		return nil, errors.New("gemist: error parsing broadcast long description")
+		longDesc, ok = schemaValue(episode, func(si *SchemaItem) string { return si.Description })
 	}
-
-	// -- Date --
-	datestr, ok := pBDate.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing broadcast date")
-		return nil, err
+		return nil, &ParseError{
+			Field: "long description",
+			Path:  pBLDesc.expr + " | " + pBLDescNPO3.expr,
+			URL:   mi.URL,
+			Err:   ErrMissing,
+		}
 	}
 
-	date, err := time.Parse(broadcastDateLayout, datestr)
-	if err != nil {
-		return nil, err
+	// -- Date --
+	var date time.Time
+	if datestr, ok := pBDate.String(n); ok {
+		date, err = time.Parse(broadcastDateLayout, datestr)
+		if err != nil {
+			return nil, withURL(invalidError("date", pBDate, datestr, err), mi.URL)
+		}
+	} else {
+		date, ok = schemaValue(schema.items(schemaEventTypes...), func(si *SchemaItem) time.Time { return si.StartDate })
+		if !ok {
+			return nil, withURL(missingError("date", pBDate), mi.URL)
+		}
 	}
 
 	// -- Type --
-	typstr, ok := pBType.String(n)
-	if !ok {
-		return nil, errors.New("gemist: error parsing broadcast type")
+	var typ BroadcastType
+	if typstr, ok := pBType.String(n); ok {
+		typ, err = parseOGType(typstr)
+		if err != nil {
+			return nil, withURL(invalidError("type", pBType, typstr, nil), mi.URL)
+		}
+	} else {
+		typ, ok = schemaBroadcastType(schema)
+		if !ok {
+			return nil, withURL(missingError("type", pBType), mi.URL)
+		}
 	}
 
-	var (
-		typ BroadcastType
-		p   broadcastParser
-	)
-
-	switch {
-	case strings.HasPrefix(typstr, "music"):
-		typ = Audio
+	var p broadcastParser = broadcastParserV
+	if typ.IsAudio() {
 		p = broadcastParserA
-	case strings.HasPrefix(typstr, "video"):
-		typ = Video
-		p = broadcastParserV
-	default:
-		return nil, fmt.Errorf("gemist: unknown broadcast type %s", typstr)
 	}
 
 	// -- Length --
+	// Live streams have no length, so it's left zero if it is missing.
+	media := schema.items(schemaMediaTypes...)
 	len, err := p.Length(n)
+	if errors.Is(err, ErrMissing) {
+		if v, ok := schemaValue(media, func(si *SchemaItem) time.Duration { return si.Duration }); ok {
+			len, err = v, nil
+		} else if typ.IsLive() {
+			err = nil
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, withURL(err, mi.URL)
 	}
 
 	// -- Media --
-	media, err := p.MediaURL(n)
+	mediaURL, err := p.MediaURL(n)
+	if errors.Is(err, ErrMissing) {
+		if v, ok := schemaValue(media, func(si *SchemaItem) string { return si.ContentURL }); ok {
+			mediaURL, err = v, nil
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, withURL(err, mi.URL)
+	}
+
+	// -- Segments --
+	segments, err := parseBroadcastSegments(n, base)
+	if err != nil {
+		return nil, withURL(err, mi.URL)
 	}
 
 	b := Broadcast{
@@ -127,31 +181,77 @@ func ParseBroadcast(r io.Reader) (*Broadcast, error) {
 		Date:            date,
 		Length:          len,
 		Type:            typ,
-		MediaURL:        media,
+		MediaURL:        mediaURL,
+		Segments:        segments,
+		Program:         parseProgramRef(schema),
+		Schema:          schema,
 	}
 
 	return &b, nil
 }
 
+// parseProgramRef returns the program of a broadcast from the last entry of
+// the breadcrumb trail of its page that links to a series, or from the series
+// in its schema.org metadata. It returns nil if there is none.
+func parseProgramRef(s *Schema) *ProgramRef {
+	if s == nil {
+		return nil
+	}
+
+	for i := len(s.Breadcrumbs) - 1; i >= 0; i-- {
+		b := s.Breadcrumbs[i]
+		if id, err := MediaIDFromURL(b.URL); err == nil && id.IsSeries() {
+			return &ProgramRef{ID: id, Title: b.Title, URL: b.URL}
+		}
+	}
+
+	for _, si := range s.items(schemaSeriesTypes...) {
+		if id, err := MediaIDFromURL(si.URL); err == nil && id.IsSeries() {
+			return &ProgramRef{ID: id, Title: si.Name, URL: si.URL}
+		}
+	}
+
+	return nil
+}
+
+// schemaBroadcastType returns the type of a broadcast from the types of the
+// schema.org items of its page.
+func schemaBroadcastType(s *Schema) (BroadcastType, bool) {
+	si := s.Item("VideoObject", "AudioObject")
+	if si == nil {
+		si = s.Item("TVEpisode", "RadioEpisode")
+	}
+	if si == nil {
+		return 0, false
+	}
+
+	switch schemaTypeName(si.Type) {
+	case "AudioObject", "RadioEpisode":
+		return Audio, true
+	}
+	return Video, true
+}
+
 type broadcastParser interface {
 	Length(*xmlpath.Node) (time.Duration, error)
 	MediaURL(*xmlpath.Node) (string, error)
 }
 
 type videoBroadcastParser struct {
-	l *xmlpath.Path
-	m *xmlpath.Path
+	l *selector
+	m *selector
 }
 
 func (p videoBroadcastParser) Length(n *xmlpath.Node) (len time.Duration, err error) {
 	lenstr, ok := p.l.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing broadcast length (video)")
+		err = missingError("length", p.l)
 		return
 	}
 
 	lenint, err := strconv.Atoi(lenstr)
 	if err != nil {
+		err = invalidError("length", p.l, lenstr, err)
 		return
 	}
 
@@ -162,74 +262,46 @@ func (p videoBroadcastParser) Length(n *xmlpath.Node) (len time.Duration, err er
 func (p videoBroadcastParser) MediaURL(n *xmlpath.Node) (url string, err error) {
 	url, ok := p.m.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing broadcast media URL (video)")
+		err = missingError("media URL", p.m)
 	}
 
 	return
 }
 
 var broadcastParserV = videoBroadcastParser{
-	l: xmlpath.MustCompile("/html/head/meta[@name='og:video:duration']/@content"),
-	m: xmlpath.MustCompile("/html/head/meta[@name='og:video']/@content"),
+	l: mustCompile("/html/head/meta[@name='og:video:duration']/@content"),
+	m: mustCompile("/html/head/meta[@name='og:video']/@content"),
 }
 
 type audioBroadcastParser struct {
-	l *xmlpath.Path
-	m *xmlpath.Path
+	l *selector
+	m *selector
 }
 
 func (p audioBroadcastParser) Length(n *xmlpath.Node) (len time.Duration, err error) {
 	lenstr, ok := p.l.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing broadcast length (audio)")
+		err = missingError("length", p.l)
 		return
 	}
 
 	len, err = parseBroadcastLength(lenstr)
+	if err != nil {
+		err = invalidError("length", p.l, lenstr, err)
+	}
 	return
 }
 
 func (p audioBroadcastParser) MediaURL(n *xmlpath.Node) (url string, err error) {
 	url, ok := p.m.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing broadcast media URL (audio)")
+		err = missingError("media URL", p.m)
 	}
 
 	return
 }
 
 var broadcastParserA = audioBroadcastParser{
-	l: xmlpath.MustCompile("//span[@class='duration']/text()"),
-	m: xmlpath.MustCompile("/html/head/meta[@name='og:audio']/@content"),
-}
-
-var broadcastLengthRegexp = regexp.MustCompile(`(\d:)?(\d+):(\d+)`)
-
-func parseBroadcastLength(str string) (len time.Duration, err error) {
-	parts := broadcastLengthRegexp.FindStringSubmatch(str)
-	// parts[0] -> matched string
-	// parts[1] -> hours
-	// parts[2] -> minutes
-	// parts[3] -> seconds
-
-	if parts[1] != "" {
-		if hour, err := strconv.Atoi(parts[1]); err != nil {
-			len += time.Duration(hour) * 60 * 60 * time.Second
-		}
-	}
-
-	min, err := strconv.Atoi(parts[2])
-	if err != nil {
-		return
-	}
-
-	len += time.Duration(min) * 60 * time.Second
-
-	sec, err := strconv.Atoi(parts[3])
-	if err != nil {
-		return
-	}
-
-	len += time.Duration(sec) * time.Second
-	return
+	l: mustCompile("//span[@class='duration']/text()"),
+	m: mustCompile("/html/head/meta[@name='og:audio']/@content"),
 }
@@ -27,6 +27,22 @@ func TestParseBroadcast_audio(t *testing.T) {
 		Length:          885000000000,
 		Type:            Audio,
 		MediaURL:        "http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3",
+		Program: &ProgramRef{
+			ID:    "POMS_S_VPRO_396280",
+			Title: "Radio Bergeijk",
+			URL:   "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280",
+		},
+		Segments: []*Segment{
+			&Segment{
+				MediaItem: MediaItem{
+					Title:     "Radio Bergeijk Radiokwis vraag 1",
+					ImageURLs: []string{"http://images.poms.omroep.nl/image/s265/c265x150/215303.png"},
+					URL:       "http://www.npo.nl/radio-bergeijk/03-04-2001/POMS_VPRO_396139/POMS_VPRO_396140",
+				},
+				ParentID:  "POMS_VPRO_396139",
+				ParentURL: "http://www.npo.nl/radio-bergeijk/03-04-2001/POMS_VPRO_396139",
+			},
+		},
 	}
 
 	b, err := ParseBroadcast(r)
@@ -51,6 +67,11 @@ func TestParseBroadcast_video(t *testing.T) {
 		Length:          3000000000000,
 		Type:            Video,
 		MediaURL:        "http://www.npo.nl/zembla/18-03-2007/VARA_101141965",
+		Program: &ProgramRef{
+			ID:    "POMS_S_VARA_099718",
+			Title: "ZEMBLA",
+			URL:   "http://www.npo.nl/zembla/POMS_S_VARA_099718",
+		},
 	}
 
 	b, err := ParseBroadcast(r)
@@ -75,12 +96,50 @@ func TestParseBroadcast_video_NPO3(t *testing.T) {
 		Length:          2100000000000,
 		Type:            Video,
 		MediaURL:        "http://www.npo.nl/radio-bergeijk-toewijding-in-beeld/25-06-2007/VPRO_1122739",
+		Program: &ProgramRef{
+			ID:    "POMS_S_VPRO_083994",
+			Title: "Radio Bergeijk, toewijding in beeld",
+			URL:   "http://www.npo.nl/radio-bergeijk-toewijding-in-beeld/POMS_S_VPRO_083994",
+		},
 	}
 
 	b, err := ParseBroadcast(r)
 	assertParsedBroadcast(t, &_b, b, err)
 }
 
+func TestParseBroadcast_live(t *testing.T) {
+	assert := assert.New(t)
+
+	// Live streams have no duration.
+	page := strings.Replace(testDataBroadcastVideo, `<meta content="video.episode" name="og:type" />`, `<meta content="video.live" name="og:type" />`, 1)
+	page = strings.Replace(page, `<meta content="3000" name="og:video:duration" />`, "", 1)
+	page = strings.Replace(page, `<meta content="PT50M0S" itemprop="duration" />`, "", 1)
+
+	b, err := ParseBroadcast(strings.NewReader(page))
+	if assert.NoError(err) {
+		assert.Equal(LiveVideo, b.Type)
+		assert.Equal(time.Duration(0), b.Length)
+		assert.Equal("http://www.npo.nl/zembla/18-03-2007/VARA_101141965", b.MediaURL)
+	}
+
+	page = strings.Replace(testDataBroadcastAudio, `<meta content="music.radio_station" name="og:type" />`, `<meta content="music.live" name="og:type" />`, 1)
+	page = strings.Replace(page, `<span class='duration'>14:45</span>`, "", 1)
+
+	b, err = ParseBroadcast(strings.NewReader(page))
+	if assert.NoError(err) {
+		assert.Equal(LiveAudio, b.Type)
+		assert.Equal(time.Duration(0), b.Length)
+		assert.Equal("http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3", b.MediaURL)
+	}
+
+	// Other broadcasts still need one.
+	page = strings.Replace(testDataBroadcastVideo, `<meta content="3000" name="og:video:duration" />`, "", 1)
+	page = strings.Replace(page, `<meta content="PT50M0S" itemprop="duration" />`, "", 1)
+
+	_, err = ParseBroadcast(strings.NewReader(page))
+	assert.ErrorIs(err, ErrMissing)
+}
+
 func assertParsedBroadcast(t *testing.T, _b, b *Broadcast, err error) {
 	assert := assert.New(t)
 	if assert.NoError(err) {
@@ -93,6 +152,8 @@ func assertParsedBroadcast(t *testing.T, _b, b *Broadcast, err error) {
 		assert.Equal(_b.Type, b.Type, "type not equal")
 		assert.Equal(_b.ImageURLs, b.ImageURLs, "image URLs not equal")
 		assert.Equal(_b.MediaURL, b.MediaURL, "media URL not equal")
+		assert.Equal(_b.Segments, b.Segments, "segments not equal")
+		assert.Equal(_b.Program, b.Program, "program not equal")
 	}
 }
 
@@ -0,0 +1,135 @@
+package gemist
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+//go:generate stringer -type=BroadcastType
+
+// IsAudio reports whether t is an audio type.
+func (t BroadcastType) IsAudio() bool {
+	return t == Audio || t == AudioSegment || t == LiveAudio
+}
+
+// IsSegment reports whether t is the type of a segment of a broadcast.
+func (t BroadcastType) IsSegment() bool {
+	return t == VideoSegment || t == AudioSegment
+}
+
+// IsLive reports whether t is the type of a live stream.
+func (t BroadcastType) IsLive() bool {
+	return t == LiveVideo || t == LiveAudio
+}
+
+// broadcastTypeNames are the text representations of the broadcast types,
+// as used by MarshalText. String returns the name of the constant instead;
+// both spellings are accepted by ParseBroadcastType.
+var broadcastTypeNames = map[BroadcastType]string{
+	Video:        "video",
+	Audio:        "audio",
+	VideoSegment: "video_segment",
+	AudioSegment: "audio_segment",
+	LiveVideo:    "live_video",
+	LiveAudio:    "live_audio",
+}
+
+// ogTypes maps the og:type values seen on NPO pages to broadcast types.
+var ogTypes = map[string]BroadcastType{
+	"video.episode":       Video,
+	"video.movie":         Video,
+	"video.tv_show":       Video,
+	"video.other":         Video,
+	"video.clip":          VideoSegment,
+	"video.live":          LiveVideo,
+	"music.radio_station": Audio,
+	"music.playlist":      Audio,
+	"music.album":         Audio,
+	"music.song":          AudioSegment,
+	"music.live":          LiveAudio,
+}
+
+// parseOGType returns the broadcast type of an og:type value. Unknown video
+// and music types are regular video and audio broadcasts.
+func parseOGType(s string) (BroadcastType, error) {
+	if t, ok := ogTypes[s]; ok {
+		return t, nil
+	}
+
+	switch {
+	case strings.HasPrefix(s, "video"):
+		return Video, nil
+	case strings.HasPrefix(s, "music"):
+		return Audio, nil
+	}
+
+	return 0, fmt.Errorf("gemist: unknown broadcast type %q", s)
+}
+
+// ParseBroadcastType parses a broadcast type. It accepts, case-insensitively,
+// the text representation ("audio", "video_segment"), the name of the
+// constant ("Audio", "VideoSegment") and og:type values ("music.song").
+func ParseBroadcastType(s string) (BroadcastType, error) {
+	for t, name := range broadcastTypeNames {
+		if strings.EqualFold(s, name) || strings.EqualFold(s, t.String()) {
+			return t, nil
+		}
+	}
+
+	return parseOGType(strings.ToLower(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t BroadcastType) MarshalText() ([]byte, error) {
+	name, ok := broadcastTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("gemist: cannot marshal %v", t)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts what
+// ParseBroadcastType accepts, so both the text representation ("live_video")
+// and the String form ("LiveVideo") are read back.
+func (t *BroadcastType) UnmarshalText(b []byte) error {
+	v, err := ParseBroadcastType(string(b))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// Set implements flag.Value. Like UnmarshalText, it accepts the String form,
+// so the default value shown in flag usage can be given back.
+func (t *BroadcastType) Set(s string) error {
+	return t.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner. It accepts text as well as the integer value
+// of the type.
+func (t *BroadcastType) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return t.UnmarshalText([]byte(v))
+	case []byte:
+		return t.UnmarshalText(v)
+	case int64:
+		if _, ok := broadcastTypeNames[BroadcastType(v)]; !ok {
+			return fmt.Errorf("gemist: unknown broadcast type %d", v)
+		}
+		*t = BroadcastType(v)
+		return nil
+	}
+	return fmt.Errorf("gemist: cannot scan %T into BroadcastType", src)
+}
+
+// Value implements driver.Valuer. It stores the text representation.
+func (t BroadcastType) Value() (driver.Value, error) {
+	b, err := t.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
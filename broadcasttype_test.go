@@ -0,0 +1,112 @@
+package gemist
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ encoding.TextMarshaler   = Video
+	_ encoding.TextUnmarshaler = (*BroadcastType)(nil)
+	_ flag.Value               = (*BroadcastType)(nil)
+	_ sql.Scanner              = (*BroadcastType)(nil)
+	_ driver.Valuer            = Video
+)
+
+func TestBroadcastType_String(t *testing.T) {
+	assert.Equal(t, "Video", Video.String())
+	assert.Equal(t, "AudioSegment", AudioSegment.String())
+	assert.Equal(t, "LiveAudio", LiveAudio.String())
+	assert.Equal(t, "BroadcastType(6)", BroadcastType(6).String())
+}
+
+func TestParseBroadcastType(t *testing.T) {
+	for s, want := range map[string]BroadcastType{
+		"audio":               Audio,
+		"Audio":               Audio,
+		"VIDEO":               Video,
+		"video_segment":       VideoSegment,
+		"AudioSegment":        AudioSegment,
+		"live_video":          LiveVideo,
+		"LiveAudio":           LiveAudio,
+		"video.episode":       Video,
+		"music.radio_station": Audio,
+		"music.song":          AudioSegment,
+		"video.clip":          VideoSegment,
+		"video.unheard_of":    Video,
+	} {
+		typ, err := ParseBroadcastType(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, want, typ, s)
+		}
+	}
+
+	_, err := ParseBroadcastType("website")
+	assert.EqualError(t, err, `gemist: unknown broadcast type "website"`)
+}
+
+func TestBroadcastType_MarshalText(t *testing.T) {
+	for typ := Video; typ <= LiveAudio; typ++ {
+		b, err := typ.MarshalText()
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		var got BroadcastType
+		assert.NoError(t, got.UnmarshalText(b))
+		assert.Equal(t, typ, got)
+
+		// The String form is read back as well.
+		got = -1
+		assert.NoError(t, got.UnmarshalText([]byte(typ.String())))
+		assert.Equal(t, typ, got)
+	}
+
+	_, err := BroadcastType(-1).MarshalText()
+	assert.Error(t, err)
+}
+
+func TestBroadcastType_Set(t *testing.T) {
+	var typ BroadcastType
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&typ, "type", "broadcast type")
+
+	assert.NoError(t, fs.Parse([]string{"-type", "audio_segment"}))
+	assert.Equal(t, AudioSegment, typ)
+
+	assert.NoError(t, fs.Parse([]string{"-type", LiveVideo.String()}))
+	assert.Equal(t, LiveVideo, typ)
+}
+
+func TestBroadcastType_Scan(t *testing.T) {
+	var typ BroadcastType
+	assert.NoError(t, typ.Scan("live_video"))
+	assert.Equal(t, LiveVideo, typ)
+	assert.NoError(t, typ.Scan([]byte("audio")))
+	assert.Equal(t, Audio, typ)
+	assert.NoError(t, typ.Scan(int64(2)))
+	assert.Equal(t, VideoSegment, typ)
+
+	assert.Error(t, typ.Scan(int64(42)))
+	assert.Error(t, typ.Scan(nil))
+	assert.Error(t, typ.Scan("podcast"))
+
+	v, err := AudioSegment.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "audio_segment", v)
+}
+
+func TestBroadcastType_Is(t *testing.T) {
+	assert.True(t, Audio.IsAudio())
+	assert.True(t, LiveAudio.IsAudio())
+	assert.False(t, VideoSegment.IsAudio())
+	assert.True(t, VideoSegment.IsSegment())
+	assert.False(t, Video.IsSegment())
+	assert.True(t, LiveVideo.IsLive())
+	assert.False(t, AudioSegment.IsLive())
+}
@@ -0,0 +1,362 @@
+package gemist
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageKind classifies the pages a Client requests, so they can be cached for
+// different times.
+type PageKind int
+
+// Page kinds.
+const (
+	OtherPage     PageKind = iota
+	ProgramPage            // program page, which changes with every new episode
+	BroadcastPage          // broadcast or segment page, which rarely changes
+	ArchivePage            // page of the broadcast or segment archive of a program
+)
+
+// PageKindOf returns the kind of the page at u.
+func PageKindOf(u *url.URL) PageKind {
+	if u.Query().Get("media_type") != "" {
+		return ArchivePage
+	}
+
+	id, err := ParseMediaID(path.Base(u.Path))
+	switch {
+	case err != nil:
+		return OtherPage
+	case id.IsSeries():
+		return ProgramPage
+	}
+	return BroadcastPage
+}
+
+// DefaultCacheMaxSize is the size of the largest response CacheTransport
+// stores, if its MaxSize is zero.
+const DefaultCacheMaxSize = 8 << 20
+
+// CacheTransport is an http.RoundTripper that caches successful responses to
+// GET requests on disk. It honours the Cache-Control and Expires headers of
+// responses and revalidates stale responses with If-None-Match and
+// If-Modified-Since, if they have an ETag or Last-Modified header.
+//
+// To cache the pages a Client gets, use it as the Transport of its
+// HTTPClient. Responses served from the cache have an X-From-Cache header.
+// Requests with a Range header and responses larger than MaxSize, such as
+// media downloads, are not cached.
+type CacheTransport struct {
+	// Dir is the directory the responses are stored in.
+	Dir string
+
+	// Transport makes the requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MinTTL is the minimum time a response is fresh, per page kind. It
+	// takes precedence over the response headers, including no-store, as
+	// npo.nl marks most pages uncacheable. Program pages can be given a
+	// short TTL and broadcast pages a long one.
+	MinTTL map[PageKind]time.Duration
+
+	// MaxSize is the size of the largest response stored. If zero,
+	// DefaultCacheMaxSize is used.
+	MaxSize int64
+
+	// Clock tells the time. If nil, the system clock is used.
+	Clock Clock
+}
+
+// cacheEntry is the metadata of a stored response.
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.transport().RoundTrip(req)
+	}
+
+	key := t.key(req.URL)
+	e, err := t.load(key)
+	if err != nil {
+		// An unreadable entry is treated as missing and overwritten.
+		e = nil
+	}
+
+	if e != nil && t.fresh(req, e) && !hasToken(req.Header, "Cache-Control", "no-cache") {
+		return t.cached(req, key, e, "hit")
+	}
+
+	creq := req
+	if e != nil {
+		creq = req.Clone(req.Context())
+		if etag := e.Header.Get("ETag"); etag != "" {
+			creq.Header.Set("If-None-Match", etag)
+		}
+		if lm := e.Header.Get("Last-Modified"); lm != "" {
+			creq.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	r, err := t.transport().RoundTrip(creq)
+	if err != nil {
+		return nil, err
+	}
+
+	if e != nil && r.StatusCode == http.StatusNotModified {
+		discard(r)
+		for k, v := range r.Header {
+			e.Header[k] = v
+		}
+		e.StoredAt = t.now()
+		if err := t.saveEntry(key, e); err != nil {
+			return nil, err
+		}
+		return t.cached(req, key, e, "revalidated")
+	}
+
+	if r.StatusCode != http.StatusOK || !t.storable(req, r) {
+		return r, nil
+	}
+
+	return t.store(req, key, r)
+}
+
+// store stores r, unless it is too large, and returns a response with the
+// same body.
+func (t *CacheTransport) store(req *http.Request, key string, r *http.Response) (*http.Response, error) {
+	max := t.MaxSize
+	if max <= 0 {
+		max = DefaultCacheMaxSize
+	}
+	if r.ContentLength > max {
+		return r, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	if err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return r, nil
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(t.path(key, ".body"), body); err != nil {
+		return nil, err
+	}
+
+	e := &cacheEntry{
+		URL:        req.URL.String(),
+		StatusCode: r.StatusCode,
+		Header:     r.Header.Clone(),
+		StoredAt:   t.now(),
+	}
+	if err := t.saveEntry(key, e); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// cached returns the stored response of e.
+func (t *CacheTransport) cached(req *http.Request, key string, e *cacheEntry, status string) (*http.Response, error) {
+	f, err := os.Open(t.path(key, ".body"))
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	h := e.Header.Clone()
+	h.Set("X-From-Cache", status)
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          f,
+		ContentLength: fi.Size(),
+		Request:       req,
+	}, nil
+}
+
+// storable reports whether the response r to req can be stored.
+func (t *CacheTransport) storable(req *http.Request, r *http.Response) bool {
+	if t.MinTTL[PageKindOf(req.URL)] > 0 {
+		return true
+	}
+	return !hasToken(r.Header, "Cache-Control", "no-store")
+}
+
+// fresh reports whether the stored response e can be used without
+// revalidation.
+func (t *CacheTransport) fresh(req *http.Request, e *cacheEntry) bool {
+	ttl := t.MinTTL[PageKindOf(req.URL)]
+	if l := freshnessLifetime(e.Header, e.StoredAt); l > ttl {
+		ttl = l
+	}
+	return t.now().Sub(e.StoredAt) < ttl
+}
+
+// freshnessLifetime returns how long a response with header h received at t
+// is fresh, according to its Cache-Control or Expires header.
+func freshnessLifetime(h http.Header, t time.Time) time.Duration {
+	if hasToken(h, "Cache-Control", "no-cache") || hasToken(h, "Cache-Control", "no-store") {
+		return 0
+	}
+
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if strings.EqualFold(name, "max-age") {
+				if n, err := strconv.Atoi(strings.Trim(val, `"`)); err == nil {
+					return time.Duration(n) * time.Second
+				}
+				return 0
+			}
+		}
+	}
+
+	if v := h.Get("Expires"); v != "" {
+		exp, err := http.ParseTime(v)
+		if err != nil {
+			return 0 // an invalid date means already expired
+		}
+
+		date := t
+		if d, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = d
+		}
+		return exp.Sub(date)
+	}
+
+	return 0
+}
+
+// hasToken reports whether the comma-separated header key of h contains
+// token, ignoring case and directive values.
+func hasToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if strings.EqualFold(name, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *CacheTransport) key(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *CacheTransport) path(key, ext string) string {
+	return filepath.Join(t.Dir, key+ext)
+}
+
+func (t *CacheTransport) load(key string) (*cacheEntry, error) {
+	data, err := os.ReadFile(t.path(key, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	// The body is written first, but can have been removed since.
+	if _, err := os.Stat(t.path(key, ".body")); err != nil {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (t *CacheTransport) saveEntry(key string, e *cacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path(key, ".json"), data)
+}
+
+func (t *CacheTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *CacheTransport) now() time.Time {
+	if t.Clock != nil {
+		return t.Clock.Now()
+	}
+	return time.Now()
+}
+
+// readCloser combines a reader with the closer of another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// writeFileAtomic writes data to name by renaming a temporary file, so that
+// readers never see a partial file.
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
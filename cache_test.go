@@ -0,0 +1,223 @@
+package gemist
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func cacheGet(t *testing.T, c *http.Client, u string, header ...string) (body, from string) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	r, err := c.Do(req)
+	require.NoError(t, err)
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	return string(b), r.Header.Get("X-From-Cache")
+}
+
+func TestCacheTransport(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "page")
+	}))
+	defer s.Close()
+
+	clock := newFakeClock()
+	c := &http.Client{Transport: &CacheTransport{Dir: t.TempDir(), Clock: clock}}
+
+	body, from := cacheGet(t, c, s.URL+"/a")
+	assert.Equal(t, "page", body)
+	assert.Equal(t, "", from)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+
+	clock.add(59 * time.Second)
+	body, from = cacheGet(t, c, s.URL+"/a")
+	assert.Equal(t, "page", body)
+	assert.Equal(t, "hit", from)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+
+	clock.add(time.Second)
+	body, from = cacheGet(t, c, s.URL+"/a")
+	assert.Equal(t, "page", body)
+	assert.Equal(t, "revalidated", from)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&n))
+
+	// Revalidation refreshes the stored response.
+	body, from = cacheGet(t, c, s.URL+"/a")
+	assert.Equal(t, "page", body)
+	assert.Equal(t, "hit", from)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&n))
+
+	// Requests can ask for revalidation.
+	_, from = cacheGet(t, c, s.URL+"/a", "Cache-Control", "no-cache")
+	assert.Equal(t, "revalidated", from)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&n))
+
+	// Other URLs are stored separately.
+	_, from = cacheGet(t, c, s.URL+"/b")
+	assert.Equal(t, "", from)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&n))
+}
+
+func TestCacheTransport_minTTL(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.Header().Set("Cache-Control", "no-store, no-cache")
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer s.Close()
+
+	clock := newFakeClock()
+	c := &http.Client{Transport: &CacheTransport{
+		Dir:   t.TempDir(),
+		Clock: clock,
+		MinTTL: map[PageKind]time.Duration{
+			ProgramPage:   time.Minute,
+			BroadcastPage: 24 * time.Hour,
+		},
+	}}
+
+	program := s.URL + "/radio-bergeijk/POMS_S_VPRO_396280"
+	broadcast := s.URL + "/radio-bergeijk/05-06-2004/POMS_VPRO_397233"
+	other := s.URL + "/live"
+
+	for i := 0; i < 2; i++ {
+		cacheGet(t, c, program)
+		cacheGet(t, c, broadcast)
+		cacheGet(t, c, other)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&n))
+
+	clock.add(time.Hour)
+	_, from := cacheGet(t, c, program)
+	assert.Equal(t, "", from)
+	body, from := cacheGet(t, c, broadcast)
+	assert.Equal(t, "/radio-bergeijk/05-06-2004/POMS_VPRO_397233", body)
+	assert.Equal(t, "hit", from)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&n))
+}
+
+func TestCacheTransport_notStored(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		switch r.URL.Path {
+		case "/large":
+			fmt.Fprint(w, strings.Repeat("x", 100))
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			http.ServeContent(w, r, "media.mp3", time.Time{}, strings.NewReader("0123456789"))
+		}
+	}))
+	defer s.Close()
+
+	c := &http.Client{Transport: &CacheTransport{Dir: t.TempDir(), MaxSize: 50}}
+
+	for i := 0; i < 2; i++ {
+		body, from := cacheGet(t, c, s.URL+"/large")
+		assert.Len(t, body, 100)
+		assert.Equal(t, "", from)
+
+		cacheGet(t, c, s.URL+"/missing")
+
+		body, _ = cacheGet(t, c, s.URL+"/media.mp3", "Range", "bytes=5-")
+		assert.Equal(t, "56789", body)
+	}
+	assert.Equal(t, int32(6), atomic.LoadInt32(&n))
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	date := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Add(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	assert.Equal(t, time.Duration(0), freshnessLifetime(h(), date))
+	assert.Equal(t, time.Minute, freshnessLifetime(h("Cache-Control", "public, max-age=60"), date))
+	assert.Equal(t, time.Duration(0), freshnessLifetime(h("Cache-Control", "max-age=60, no-cache"), date))
+	assert.Equal(t, time.Hour, freshnessLifetime(h(
+		"Date", "Thu, 01 Jan 2015 12:00:00 GMT",
+		"Expires", "Thu, 01 Jan 2015 13:00:00 GMT",
+	), date.Add(time.Minute)))
+	assert.Equal(t, 30*time.Minute, freshnessLifetime(h("Expires", "Thu, 01 Jan 2015 12:30:00 GMT"), date))
+	assert.Equal(t, time.Minute, freshnessLifetime(h(
+		"Cache-Control", "max-age=60",
+		"Expires", "Thu, 01 Jan 2015 13:00:00 GMT",
+	), date))
+	assert.Equal(t, time.Duration(0), freshnessLifetime(h("Expires", "0"), date))
+}
+
+func TestPageKindOf(t *testing.T) {
+	for rawurl, want := range map[string]PageKind{
+		"http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280":                                     ProgramPage,
+		"http://www.npo.nl/radio-bergeijk/05-06-2004/POMS_VPRO_397233":                            BroadcastPage,
+		"http://www.npo.nl/radio-bergeijk/06-10-2007/POMS_VPRO_396637/POMS_VPRO_396638":           BroadcastPage,
+		"http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280/search?media_type=broadcast&start=8": ArchivePage,
+		"http://ida.omroep.nl/npoplayer/i.js":                                                     OtherPage,
+	} {
+		u, err := url.Parse(rawurl)
+		require.NoError(t, err)
+		assert.Equal(t, want, PageKindOf(u), rawurl)
+	}
+}
+
+func TestClient_GetProgram_cached(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		fmt.Fprint(w, testProgramPage(2, 1))
+	}))
+	defer s.Close()
+
+	c := &Client{
+		BaseURL: s.URL,
+		HTTPClient: &http.Client{Transport: &CacheTransport{
+			Dir:    t.TempDir(),
+			MinTTL: map[PageKind]time.Duration{ProgramPage: time.Minute},
+		}},
+	}
+
+	for i := 0; i < 3; i++ {
+		p, err := c.GetProgram(context.Background(), "POMS_S_VPRO_396280")
+		require.NoError(t, err)
+		assert.Equal(t, 2, p.NumBroadcasts())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
@@ -0,0 +1,206 @@
+package gemist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the base URL of Uitzending Gemist.
+const DefaultBaseURL = "http://www.npo.nl"
+
+// A Client gets and parses Uitzending Gemist pages. The zero value is ready to
+// use.
+type Client struct {
+	// HTTPClient is used to make requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// BaseURL is the URL pages are requested from. Paths and page URLs on
+	// www.npo.nl are resolved against it, which allows using a local stand-in
+	// server. If empty, DefaultBaseURL is used.
+	BaseURL string
+
+	// PlayerURL is the base URL of the NPO player API, used to resolve
+	// streams. If empty, DefaultPlayerURL is used.
+	PlayerURL string
+
+	// UserAgent is sent with every request if not empty.
+	UserAgent string
+
+	// Header contains headers sent with every request.
+	Header http.Header
+
+	// RateLimit limits the requests to each host. If nil, requests are not
+	// limited.
+	RateLimit *RateLimit
+
+	// Retry is the policy for retrying failed requests. If nil, requests
+	// are not retried.
+	Retry *RetryPolicy
+
+	// OnAttempt, if set, is called after every attempt of a request.
+	OnAttempt func(Attempt)
+
+	limitMu  sync.Mutex
+	limiters map[string]*hostLimiter
+}
+
+// DefaultClient is the Client used by GetBroadcast, GetProgram and the
+// Resolve methods.
+var DefaultClient = &Client{}
+
+// GetBroadcast gets the page content from url, parses it and returns a Broadcast.
+// url can also be the media ID of the broadcast.
+func (c *Client) GetBroadcast(ctx context.Context, url string) (*Broadcast, error) {
+	r, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := parseBroadcast(r.Body, c.baseURL())
+	if err != nil {
+		return nil, withURL(err, r.Request.URL.String())
+	}
+
+	return b, nil
+}
+
+// GetProgram gets the page content from url, parses it and returns a Program.
+// url can also be the media ID of the program.
+func (c *Client) GetProgram(ctx context.Context, url string) (*Program, error) {
+	r, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	p, err := parseProgram(r.Body, c.baseURL(), responseDate(r))
+	if err != nil {
+		return nil, withURL(err, r.Request.URL.String())
+	}
+
+	return p, nil
+}
+
+// get requests rawurl. The caller must close the body of the response. A
+// response with a non-2xx status code is returned as an *HTTPError.
+func (c *Client) get(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		discard(r)
+		return nil, newHTTPError(r)
+	}
+
+	return r, nil
+}
+
+// responseDate returns the time response r was served, from its Date header,
+// or the current time if it has none.
+func responseDate(r *http.Response) time.Time {
+	if d, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		return d
+	}
+	return time.Now()
+}
+
+// getBytes requests rawurl and returns the body of the response and the URL
+// it was read from, after redirects.
+func (c *Client) getBytes(ctx context.Context, rawurl string) ([]byte, string, error) {
+	r, err := c.get(ctx, rawurl)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, r.Request.URL.String(), nil
+}
+
+// newRequest returns a GET request for rawurl with the headers of the client.
+func (c *Client) newRequest(ctx context.Context, rawurl string) (*http.Request, error) {
+	u, err := c.pageURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	return req, nil
+}
+
+// discard closes the body of r after draining a bit of it, so the connection
+// can be reused.
+func discard(r *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(r.Body, 4<<10))
+	r.Body.Close()
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimSuffix(c.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+// npoHosts are the hosts of page URLs that are resolved against the base URL.
+var npoHosts = map[string]bool{
+	"www.npo.nl": true,
+	"npo.nl":     true,
+}
+
+// pageURL returns the URL rawurl is requested from. rawurl can also be a media
+// ID. Absolute URLs on other hosts than npoHosts are returned unchanged.
+func (c *Client) pageURL(rawurl string) (string, error) {
+	u, err := url.Parse(mediaPath(rawurl))
+	if err != nil {
+		return "", err
+	}
+
+	if u.IsAbs() && !npoHosts[u.Host] {
+		return rawurl, nil
+	}
+
+	base, err := url.Parse(c.baseURL())
+	if err != nil {
+		return "", err
+	}
+
+	pu := base.JoinPath(u.Path)
+	pu.RawQuery = u.RawQuery
+	return pu.String(), nil
+}
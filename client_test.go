@@ -0,0 +1,80 @@
+package gemist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetProgram(t *testing.T) {
+	assert := assert.New(t)
+
+	var req *http.Request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		w.Write([]byte(testDataProgramBroadcast))
+	}))
+	defer s.Close()
+
+	c := Client{
+		BaseURL:   s.URL,
+		UserAgent: "gemist-test",
+		Header:    http.Header{"Accept-Language": {"nl"}},
+	}
+
+	p, err := c.GetProgram(context.Background(), "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280")
+	if assert.NoError(err) {
+		assert.Equal(s.URL+"/radio-bergeijk-de-allerlaatste/06-10-2007/POMS_VPRO_396279", p.bs[0].URL, "broadcast proxy URL not resolved against base URL")
+	}
+	if assert.NotNil(req, "no request made") {
+		assert.Equal("/radio-bergeijk/POMS_S_VPRO_396280", req.URL.Path, "request path not equal")
+		assert.Equal("gemist-test", req.Header.Get("User-Agent"), "user agent not set")
+		assert.Equal("nl", req.Header.Get("Accept-Language"), "default header not set")
+	}
+}
+
+func TestClient_GetBroadcast_canceled(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request made with canceled context")
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := Client{BaseURL: s.URL}
+	_, err := c.GetBroadcast(ctx, "/radio-bergeijk/03-04-2001/POMS_VPRO_396139")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_pageURL(t *testing.T) {
+	tests := []struct {
+		base, in, out string
+	}{
+		{"", "/zembla/18-03-2007/VARA_101141965", "http://www.npo.nl/zembla/18-03-2007/VARA_101141965"},
+		{"", "http://www.npo.nl/zembla/18-03-2007/VARA_101141965", "http://www.npo.nl/zembla/18-03-2007/VARA_101141965"},
+		{"http://127.0.0.1:8080/", "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280?media_type=broadcast", "http://127.0.0.1:8080/radio-bergeijk/POMS_S_VPRO_396280?media_type=broadcast"},
+		{"http://127.0.0.1:8080", "http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3", "http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3"},
+	}
+
+	for _, tt := range tests {
+		c := Client{BaseURL: tt.base}
+		u, err := c.pageURL(tt.in)
+		if assert.NoError(t, err, tt.in) {
+			assert.Equal(t, tt.out, u, tt.in)
+		}
+	}
+}
+
+func TestResponseDate(t *testing.T) {
+	r := &http.Response{Header: http.Header{"Date": {"Sat, 06 Oct 2007 16:32:00 GMT"}}}
+	assert.True(t, time.Date(2007, time.October, 6, 16, 32, 0, 0, time.UTC).Equal(responseDate(r)), "date not equal")
+
+	before := time.Now()
+	d := responseDate(&http.Response{Header: http.Header{}})
+	assert.False(t, d.Before(before), "current time not used without a Date header")
+}
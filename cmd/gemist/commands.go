@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwlnetnl/gemist"
+)
+
+// env holds the global flags and output streams of a run.
+type env struct {
+	stdout, stderr io.Writer
+
+	json    bool
+	baseURL string
+}
+
+// flags registers the global flags on fs.
+func (e *env) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&e.json, "json", e.json, "write output as JSON")
+	fs.StringVar(&e.baseURL, "base-url", e.baseURL, "base `URL` of the NPO website")
+}
+
+// flagSet returns a flag set for cmd with the global flags registered.
+func (e *env) flagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	e.flags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: gemist %s %s\n\n%s.\n\nflags:\n", cmd.name, cmd.args, cmd.short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks that exactly one argument remains.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
+
+func (e *env) client() *gemist.Client {
+	return &gemist.Client{BaseURL: e.baseURL}
+}
+
+func (e *env) writeJSON(v interface{}) error {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runInfo(ctx context.Context, e *env, cmd *command, args []string) error {
+	fs := e.flagSet(cmd)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c := e.client()
+	arg := fs.Arg(0)
+
+	if isProgram(arg) {
+		p, err := c.GetProgram(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return e.printProgram(p)
+	}
+
+	b, err := c.GetBroadcast(ctx, arg)
+	if err != nil {
+		return err
+	}
+	return e.printBroadcast(b)
+}
+
+// isProgram reports whether s is the media ID or URL of a program.
+func isProgram(s string) bool {
+	id, err := gemist.ParseMediaID(s)
+	if err != nil {
+		id, err = gemist.MediaIDFromURL(s)
+	}
+	return err == nil && id.IsSeries()
+}
+
+func (e *env) printProgram(p *gemist.Program) error {
+	if e.json {
+		return e.writeJSON(p)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(w, "Title:\t%s\n", p.Title)
+	fmt.Fprintf(w, "ID:\t%s\n", p.ID())
+	fmt.Fprintf(w, "URL:\t%s\n", p.URL)
+	fmt.Fprintf(w, "Broadcasts:\t%d\n", p.NumBroadcasts())
+	fmt.Fprintf(w, "Segments:\t%d\n", p.NumSegments())
+	fmt.Fprintf(w, "Description:\t%s\n", p.Description)
+	return w.Flush()
+}
+
+func (e *env) printBroadcast(b *gemist.Broadcast) error {
+	if e.json {
+		return e.writeJSON(b)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(w, "Title:\t%s\n", b.Title)
+	fmt.Fprintf(w, "ID:\t%s\n", b.ID())
+	fmt.Fprintf(w, "URL:\t%s\n", b.URL)
+	fmt.Fprintf(w, "Type:\t%s\n", b.Type)
+	fmt.Fprintf(w, "Date:\t%s\n", b.Date.Format(time.RFC3339))
+	fmt.Fprintf(w, "Length:\t%s\n", b.Length)
+	if b.Program != nil {
+		fmt.Fprintf(w, "Program:\t%s (%s)\n", b.Program.Title, b.Program.ID)
+	}
+	if b.MediaURL != "" {
+		fmt.Fprintf(w, "Media URL:\t%s\n", b.MediaURL)
+	}
+	if len(b.Segments) > 0 {
+		fmt.Fprintf(w, "Segments:\t%d\n", len(b.Segments))
+	}
+	desc := b.LongDescription
+	if desc == "" {
+		desc = b.Description
+	}
+	fmt.Fprintf(w, "Description:\t%s\n", desc)
+	return w.Flush()
+}
+
+func runEpisodes(ctx context.Context, e *env, cmd *command, args []string) error {
+	fs := e.flagSet(cmd)
+	all := fs.Bool("all", false, "list all broadcasts by following the archive pages")
+	limit := fs.Int("limit", 0, "list at most `n` broadcasts (0 means no limit)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	bps, err := e.episodes(ctx, fs.Arg(0), *all)
+	if err != nil {
+		return err
+	}
+
+	if *limit > 0 && len(bps) > *limit {
+		bps = bps[:*limit]
+	}
+
+	if e.json {
+		return e.writeJSON(bps)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 8, 2, ' ', 0)
+	for _, bp := range bps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", bp.Date.Format("2006-01-02 15:04"), bp.Length, bp.ID(), bp.Title)
+	}
+	return w.Flush()
+}
+
+// episodes gets the broadcasts of a program, either those listed on the
+// program page or, if all is set, those of all archive pages.
+func (e *env) episodes(ctx context.Context, program string, all bool) ([]*gemist.BroadcastProxy, error) {
+	c := e.client()
+
+	if all {
+		return c.GetBroadcasts(ctx, program, func(p gemist.ArchiveProgress) {
+			fmt.Fprintf(e.stderr, "\rpage %d: %d of %d broadcasts", p.Pages, p.Items, p.Total)
+			if p.Items >= p.Total {
+				fmt.Fprintln(e.stderr)
+			}
+		})
+	}
+
+	p, err := c.GetProgram(ctx, program)
+	if err != nil {
+		return nil, err
+	}
+	return p.Broadcasts(), nil
+}
+
+func runDownload(ctx context.Context, e *env, cmd *command, args []string) error {
+	fs := e.flagSet(cmd)
+	quality := fs.String("quality", "best", "stream `quality`: best, worst, audio or a maximum height such as 720p")
+	out := fs.String("o", "", "write to `file` instead of a name derived from the media ID")
+	quiet := fs.Bool("q", false, "do not report progress")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	policy, err := parseQuality(*quality)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "gemist download: %v\n", err)
+		fs.Usage()
+		return errUsage
+	}
+
+	c := e.client()
+	b, err := c.GetBroadcast(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if len(b.Streams) == 0 {
+		if err := c.ResolveStreams(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	s, err := b.SelectStream(policy)
+	if err != nil {
+		return err
+	}
+
+	dst := *out
+	if dst == "" {
+		dst = string(b.ID())
+		if dst == "" {
+			dst = "broadcast"
+		}
+		dst += "." + s.Container
+	}
+
+	d := gemist.Downloader{Client: c, Policy: policy}
+	if !*quiet {
+		d.Progress = func(p gemist.DownloadProgress) {
+			fmt.Fprintf(e.stderr, "\r%s", formatProgress(p))
+		}
+	}
+
+	err = d.Download(ctx, b, dst)
+	if !*quiet {
+		fmt.Fprintln(e.stderr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if e.json {
+		return e.writeJSON(struct {
+			File   string
+			Stream gemist.Stream
+		}{dst, s})
+	}
+
+	fmt.Fprintln(e.stdout, dst)
+	return nil
+}
+
+// parseQuality parses a stream quality flag into a policy.
+func parseQuality(s string) (gemist.StreamPolicy, error) {
+	switch strings.ToLower(s) {
+	case "best":
+		return gemist.Best, nil
+	case "worst":
+		return gemist.Worst, nil
+	case "audio":
+		return gemist.AudioOnly, nil
+	}
+
+	h, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(s), "p"))
+	if err != nil || h <= 0 {
+		return gemist.StreamPolicy{}, fmt.Errorf("invalid quality %q", s)
+	}
+	return gemist.MaxHeight(h), nil
+}
+
+// formatProgress formats download progress for a terminal.
+func formatProgress(p gemist.DownloadProgress) string {
+	s := fmt.Sprintf("%.1f MB", float64(p.Bytes)/1e6)
+	if p.Total > 0 {
+		s += fmt.Sprintf(" of %.1f MB (%.0f%%)", float64(p.Total)/1e6, 100*float64(p.Bytes)/float64(p.Total))
+	}
+	s += fmt.Sprintf(", %.1f MB/s", p.Rate/1e6)
+	if p.ETA >= 0 {
+		s += fmt.Sprintf(", %s left", p.ETA.Round(time.Second))
+	}
+	return s
+}
+
+func runFeed(ctx context.Context, e *env, cmd *command, args []string) error {
+	fs := e.flagSet(cmd)
+	all := fs.Bool("all", false, "include all broadcasts by following the archive pages")
+	out := fs.String("o", "", "write to `file` instead of standard output")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c := e.client()
+	p, err := c.GetProgram(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	bps := p.Broadcasts()
+	if *all {
+		bps, err = e.episodes(ctx, fs.Arg(0), true)
+		if err != nil {
+			return err
+		}
+	}
+
+	bs := make([]*gemist.Broadcast, 0, len(bps))
+	for _, r := range c.ResolveMany(ctx, bps, gemist.ResolveOptions{}) {
+		var merr *gemist.MismatchError
+		if r.Err != nil && !errors.As(r.Err, &merr) {
+			return r.Err
+		}
+		bs = append(bs, r.Broadcast)
+	}
+
+	f := gemist.Feed{Program: p, Broadcasts: bs}
+	if *out == "" {
+		return f.Write(e.stdout)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
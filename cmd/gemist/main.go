@@ -0,0 +1,149 @@
+// Command gemist inspects, lists and downloads Uitzending Gemist content.
+//
+// Usage:
+//
+//	gemist [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	info <url|id>         print a broadcast or program
+//	episodes <program>    list the broadcasts of a program
+//	download <url|id>     download the media of a broadcast
+//	feed <program>        write a podcast feed of a program
+//
+// The flags -json and -base-url are accepted before and after the command.
+//
+// Exit codes are 0 on success, 1 on other errors, 2 on usage errors, 3 if
+// content is not found or unavailable, 4 if a page could not be parsed and 5
+// on network and HTTP errors.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+
+	"github.com/dwlnetnl/gemist"
+)
+
+// Exit codes.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitParse       = 4
+	exitNetwork     = 5
+	exitInterrupted = 130
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// errUsage reports invalid command-line usage. The usage has already been
+// printed when it is returned.
+var errUsage = errors.New("usage error")
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(ctx context.Context, e *env, cmd *command, args []string) error
+}
+
+var commands = []*command{
+	{"info", "<url|id>", "print a broadcast or program", runInfo},
+	{"episodes", "[-all] [-limit n] <program>", "list the broadcasts of a program", runEpisodes},
+	{"download", "[-quality q] [-o file] <url|id>", "download the media of a broadcast", runDownload},
+	{"feed", "[-all] [-o file] <program>", "write a podcast feed of a program", runFeed},
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("gemist", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	e.flags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gemist [flags] <command> [arguments]")
+		fmt.Fprintln(stderr, "\ncommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-10s %s\n", cmd.name, cmd.short)
+		}
+		fmt.Fprintln(stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		err := cmd.run(ctx, e, cmd, fs.Args()[1:])
+		if err == nil {
+			return exitOK
+		}
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		if err != errUsage {
+			fmt.Fprintf(stderr, "gemist %s: %v\n", name, err)
+		}
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(stderr, "gemist: unknown command %q\n", name)
+	fs.Usage()
+	return exitUsage
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var (
+		herr *gemist.HTTPError
+		uerr *url.Error
+		nerr net.Error
+	)
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, gemist.ErrNotFound),
+		errors.Is(err, gemist.ErrUnavailable),
+		errors.Is(err, gemist.ErrNoStreams):
+		return exitNotFound
+	case errors.Is(err, gemist.ErrLayout):
+		return exitParse
+	case errors.As(err, &herr), errors.As(err, &uerr), errors.As(err, &nerr):
+		return exitNetwork
+	}
+
+	return exitError
+}
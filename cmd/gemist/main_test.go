@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dwlnetnl/gemist"
+	"github.com/stretchr/testify/assert"
+)
+
+func runArgs(args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_usage(t *testing.T) {
+	code, _, stderr := runArgs()
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "usage: gemist")
+
+	code, _, stderr = runArgs("frobnicate")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+
+	code, _, stderr = runArgs("info")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "usage: gemist info <url|id>")
+
+	code, _, _ = runArgs("download", "-quality", "huge", "POMS_VPRO_396140")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runArgs("-h")
+	assert.Equal(t, exitOK, code)
+}
+
+func TestRun_notFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	code, _, stderr := runArgs("-base-url", ts.URL, "info", "POMS_VPRO_396140")
+	assert.Equal(t, exitNotFound, code)
+	assert.Contains(t, stderr, "gemist info:")
+
+	code, _, _ = runArgs("episodes", "-base-url", ts.URL, "POMS_S_VPRO_396280")
+	assert.Equal(t, exitNotFound, code)
+}
+
+func TestRun_network(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	code, _, _ := runArgs("-json", "-base-url", ts.URL, "info", "POMS_S_VPRO_396280")
+	assert.Equal(t, exitNetwork, code)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitUsage, exitCode(errUsage))
+	assert.Equal(t, exitInterrupted, exitCode(fmt.Errorf("get: %w", context.Canceled)))
+	assert.Equal(t, exitNotFound, exitCode(&gemist.HTTPError{StatusCode: 404}))
+	assert.Equal(t, exitNotFound, exitCode(&gemist.HTTPError{StatusCode: 410}))
+	assert.Equal(t, exitNotFound, exitCode(gemist.ErrNoStreams))
+	assert.Equal(t, exitParse, exitCode(&gemist.ParseError{Field: "title", Err: gemist.ErrMissing}))
+	assert.Equal(t, exitNetwork, exitCode(&gemist.HTTPError{StatusCode: 500}))
+	assert.Equal(t, exitError, exitCode(errors.New("other")))
+}
+
+func TestParseQuality(t *testing.T) {
+	for s, want := range map[string]gemist.StreamPolicy{
+		"best":  gemist.Best,
+		"Worst": gemist.Worst,
+		"audio": gemist.AudioOnly,
+		"720p":  gemist.MaxHeight(720),
+		"360":   gemist.MaxHeight(360),
+	} {
+		p, err := parseQuality(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, p, s)
+	}
+
+	_, err := parseQuality("0p")
+	assert.Error(t, err)
+}
@@ -0,0 +1,292 @@
+package gemist
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadProgress reports the progress of a download.
+type DownloadProgress struct {
+	Bytes int64         // bytes downloaded, including resumed bytes
+	Total int64         // total size in bytes, or -1 if unknown
+	Rate  float64       // bytes per second since the download (re)started
+	ETA   time.Duration // estimated time remaining, or -1 if unknown
+}
+
+// PartSuffix is appended to the destination path of a download while it is in
+// progress.
+const PartSuffix = ".part"
+
+// A Downloader downloads broadcast media to disk. Interrupted downloads are
+// resumed from the partial file. The zero value is ready to use.
+type Downloader struct {
+	// Client is used to resolve streams and make requests. If nil,
+	// DefaultClient is used.
+	Client *Client
+
+	// Policy selects the stream to download out of the resolved streams of
+	// a broadcast.
+	Policy StreamPolicy
+
+	// Progress is called with the progress of a download at most once per
+	// ProgressInterval, and once when it completes, if not nil.
+	Progress         func(DownloadProgress)
+	ProgressInterval time.Duration // if zero, a second is used
+
+	// Workers is the number of segments of an HLS stream requested
+	// concurrently. If zero, 4 is used.
+	Workers int
+
+	// Retries is the number of times a failing HLS segment request is
+	// retried. If zero, 3 is used; if negative, requests are not retried.
+	// If the client has a retry policy, it retries the requests instead
+	// and Retries is ignored.
+	Retries int
+}
+
+// Download downloads the media of broadcast b to the file dst. It uses a zero
+// Downloader.
+func Download(ctx context.Context, b *Broadcast, dst string) error {
+	var d Downloader
+	return d.Download(ctx, b, dst)
+}
+
+// Download downloads the media of broadcast b to the file dst. If b has no
+// resolved streams, they are resolved first. The stream is selected with the
+// policy of d.
+func (d *Downloader) Download(ctx context.Context, b *Broadcast, dst string) error {
+	if len(b.Streams) == 0 {
+		if err := d.client().ResolveStreams(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	s, err := SelectStream(b.Streams, d.Policy)
+	if err != nil {
+		return err
+	}
+
+	switch s.Protocol {
+	case ProtocolHTTP:
+		return d.DownloadURL(ctx, s.URL, dst)
+	case ProtocolHLS:
+		return d.DownloadHLS(ctx, s.URL, dst)
+	}
+
+	return fmt.Errorf("gemist: downloading %s streams is not supported", s.Protocol)
+}
+
+// DownloadURL downloads the file at url to dst. The file is written to dst
+// with PartSuffix appended, and renamed to dst when its size is verified
+// against the size reported by the server. If the partial file exists, the
+// download is resumed from its end using an HTTP range request.
+func (d *Downloader) DownloadURL(ctx context.Context, url, dst string) error {
+	part := dst + PartSuffix
+
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	// abort removes a partial file that is still empty, so a download that
+	// does not start leaves nothing behind.
+	abort := func(err error) error {
+		if offset == 0 {
+			f.Close()
+			os.Remove(part)
+		}
+		return err
+	}
+
+	c := d.client()
+	req, err := c.newRequest(ctx, url)
+	if err != nil {
+		return abort(err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	r, err := c.send(req)
+	if err != nil {
+		return abort(err)
+	}
+	defer r.Body.Close()
+
+	total := int64(-1)
+	switch {
+	case r.StatusCode == http.StatusPartialContent:
+		start, size, ok := parseContentRange(r.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return abort(fmt.Errorf("gemist: unexpected content range %q resuming %s at %d", r.Header.Get("Content-Range"), url, offset))
+		}
+		total = size
+
+	case r.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file can already be complete.
+		_, size, ok := parseContentRange(r.Header.Get("Content-Range"))
+		if !ok || size != offset {
+			return newHTTPError(r)
+		}
+		return d.finish(f, part, dst, DownloadProgress{Bytes: offset, Total: size})
+
+	case r.StatusCode >= 200 && r.StatusCode <= 299:
+		// The server ignored the range, so start over.
+		if offset > 0 {
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset = 0
+		}
+		total = r.ContentLength
+
+	default:
+		return abort(newHTTPError(r))
+	}
+
+	pw := d.newProgressWriter(f, offset, total)
+	_, err = io.Copy(pw, r.Body)
+	if err != nil {
+		return err
+	}
+
+	if total >= 0 && pw.p.Bytes != total {
+		return fmt.Errorf("gemist: incomplete download of %s: %d of %d bytes", url, pw.p.Bytes, total)
+	}
+
+	return d.finish(f, part, dst, pw.progress())
+}
+
+// finish syncs and closes the partial file f and renames it to dst.
+func (d *Downloader) finish(f *os.File, part, dst string, p DownloadProgress) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return err
+	}
+
+	if d.Progress != nil {
+		p.ETA = 0
+		d.Progress(p)
+	}
+	return nil
+}
+
+func (d *Downloader) client() *Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return DefaultClient
+}
+
+// parseContentRange parses a Content-Range header of the form
+// "bytes start-end/size" or "bytes */size". The size is -1 if unknown.
+func parseContentRange(s string) (start, size int64, ok bool) {
+	s, found := strings.CutPrefix(s, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+
+	rng, sz, found := strings.Cut(s, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	size = -1
+	if sz != "*" {
+		var err error
+		if size, err = strconv.ParseInt(sz, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if rng == "*" {
+		return 0, size, true
+	}
+
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// A progressWriter counts the bytes written to w and reports progress.
+type progressWriter struct {
+	w        io.Writer
+	d        *Downloader
+	p        DownloadProgress
+	offset   int64 // bytes already downloaded when started
+	start    time.Time
+	reported time.Time
+}
+
+func (d *Downloader) newProgressWriter(w io.Writer, offset, total int64) *progressWriter {
+	now := time.Now()
+	return &progressWriter{
+		w:        w,
+		d:        d,
+		p:        DownloadProgress{Bytes: offset, Total: total},
+		offset:   offset,
+		start:    now,
+		reported: now,
+	}
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.Bytes += int64(n)
+
+	if pw.d.Progress != nil {
+		interval := pw.d.ProgressInterval
+		if interval == 0 {
+			interval = time.Second
+		}
+		if now := time.Now(); now.Sub(pw.reported) >= interval {
+			pw.reported = now
+			pw.d.Progress(pw.progress())
+		}
+	}
+
+	return n, err
+}
+
+// progress returns the progress with the rate and ETA filled in.
+func (pw *progressWriter) progress() DownloadProgress {
+	p := pw.p
+	p.ETA = -1
+
+	elapsed := time.Since(pw.start).Seconds()
+	if elapsed > 0 {
+		p.Rate = float64(p.Bytes-pw.offset) / elapsed
+	}
+	if p.Total >= 0 && p.Rate > 0 {
+		p.ETA = time.Duration(float64(p.Total-p.Bytes) / p.Rate * float64(time.Second))
+	}
+
+	return p
+}
@@ -0,0 +1,147 @@
+package gemist
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDataMedia = bytes.Repeat([]byte("ID3 Radio Bergeijk "), 1000)
+
+func mediaServer(t *testing.T, ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ranges != nil {
+			*ranges = append(*ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, "POMS_VPRO_396139.mp3", time.Time{}, bytes.NewReader(testDataMedia))
+	}))
+}
+
+func TestDownloader_Download(t *testing.T) {
+	assert := assert.New(t)
+
+	s := mediaServer(t, nil)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "POMS_VPRO_396139.mp3")
+	b := Broadcast{Type: Audio, MediaURL: s.URL + "/vpro/29/08/57/39/POMS_VPRO_396139.mp3"}
+
+	var ps []DownloadProgress
+	d := Downloader{Progress: func(p DownloadProgress) { ps = append(ps, p) }}
+	require.NoError(t, d.Download(context.Background(), &b, dst))
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(testDataMedia, data, "downloaded data not equal")
+
+	_, err = os.Stat(dst + PartSuffix)
+	assert.True(os.IsNotExist(err), "partial file not removed")
+
+	if assert.NotEmpty(ps, "no progress reported") {
+		p := ps[len(ps)-1]
+		assert.Equal(int64(len(testDataMedia)), p.Bytes, "progress bytes not equal")
+		assert.Equal(int64(len(testDataMedia)), p.Total, "progress total not equal")
+		assert.Equal(time.Duration(0), p.ETA, "progress ETA not zero")
+	}
+}
+
+func TestDownloader_DownloadURL_resume(t *testing.T) {
+	assert := assert.New(t)
+
+	var ranges []string
+	s := mediaServer(t, &ranges)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "POMS_VPRO_396139.mp3")
+	require.NoError(t, os.WriteFile(dst+PartSuffix, testDataMedia[:5000], 0644))
+
+	var d Downloader
+	require.NoError(t, d.DownloadURL(context.Background(), s.URL+"/POMS_VPRO_396139.mp3", dst))
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(testDataMedia, data, "resumed data not equal")
+	assert.Equal([]string{"bytes=5000-"}, ranges, "range not requested")
+
+	// A complete partial file is renamed.
+	ranges = nil
+	require.NoError(t, os.WriteFile(dst+PartSuffix, testDataMedia, 0644))
+	require.NoError(t, d.DownloadURL(context.Background(), s.URL+"/POMS_VPRO_396139.mp3", dst))
+	assert.Equal([]string{"bytes=19000-"}, ranges, "range not requested")
+	data, err = os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(testDataMedia, data, "complete data not equal")
+}
+
+func TestDownloader_DownloadURL_noRange(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testDataMedia)
+	}))
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "POMS_VPRO_396139.mp3")
+	require.NoError(t, os.WriteFile(dst+PartSuffix, []byte(strings.Repeat("x", 7000)), 0644))
+
+	var d Downloader
+	require.NoError(t, d.DownloadURL(context.Background(), s.URL, dst))
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, testDataMedia, data, "restarted data not equal")
+}
+
+func TestDownloader_DownloadURL_notFound(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "POMS_VPRO_396139.mp3")
+
+	var d Downloader
+	err := d.DownloadURL(context.Background(), s.URL, dst)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, p := range []string{dst, dst + PartSuffix} {
+		_, err = os.Stat(p)
+		assert.True(t, os.IsNotExist(err), "%s created", p)
+	}
+
+	// Neither is the partial file left behind when the server is down.
+	s.Close()
+	err = d.DownloadURL(context.Background(), s.URL, dst)
+	assert.Error(t, err)
+	_, err = os.Stat(dst + PartSuffix)
+	assert.True(t, os.IsNotExist(err), "partial file created")
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 5000-18999/19000", 5000, 19000, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */19000", 0, 19000, true},
+		{"bytes 5000/19000", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		if tt.ok {
+			assert.Equal(t, tt.start, start, tt.in)
+			assert.Equal(t, tt.size, size, tt.in)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gemist
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockLengthRegexp matches a length as shown on a clock, anywhere in a
+// string. Submatches:
+// 1 -> hours or minutes
+// 2 -> minutes or seconds
+// 3 -> seconds, optional
+var clockLengthRegexp = regexp.MustCompile(`(?:^|[^\d:])(\d{1,4}):(\d{2})(?::(\d{2}))?(?:$|[^\d:])`)
+
+// textLengthRegexp matches a length in Dutch or English text, like "35 min"
+// or "1 uur 5 min". Submatches are the hours, minutes and seconds.
+var textLengthRegexp = regexp.MustCompile(`(?i)^(?:(\d{1,4})\s*(?:uur|uren|u|hours?|hrs?|h)\.?\s*)?` +
+	`(?:(\d{1,4})\s*(?:minuten|minuut|minutes?|min|m)\.?\s*)?` +
+	`(?:(\d{1,6})\s*(?:seconden|seconde|seconds?|sec|s)\.?)?$`)
+
+// parseBroadcastLength parses the length of a broadcast as shown on pages. It
+// accepts clock lengths (H:MM:SS, HH:MM:SS or MM:SS) surrounded by other
+// text, ISO 8601 durations (PT50M0S) and text like "35 min".
+func parseBroadcastLength(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "P") {
+		return parseISODuration(s)
+	}
+
+	if parts := clockLengthRegexp.FindStringSubmatch(s); parts != nil {
+		return parseClockLength(parts[1], parts[2], parts[3])
+	}
+
+	if parts := textLengthRegexp.FindStringSubmatch(s); parts != nil && parts[0] != "" {
+		var d time.Duration
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+			if parts[i+1] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(parts[i+1])
+			if err != nil {
+				return 0, err
+			}
+			d += time.Duration(n) * unit
+		}
+		return d, nil
+	}
+
+	return 0, fmt.Errorf("gemist: invalid length %q", s)
+}
+
+// parseClockLength returns the length of a clock with fields a:b or a:b:c.
+func parseClockLength(a, b, c string) (time.Duration, error) {
+	fields := []string{a, b}
+	units := []time.Duration{time.Minute, time.Second}
+	if c != "" {
+		fields = append(fields, c)
+		units = []time.Duration{time.Hour, time.Minute, time.Second}
+	}
+
+	var d time.Duration
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return 0, err
+		}
+		if i > 0 && n >= 60 {
+			return 0, fmt.Errorf("gemist: invalid length %q", strings.Join(fields, ":"))
+		}
+		d += time.Duration(n) * units[i]
+	}
+
+	return d, nil
+}
+
+// formatISODuration formats d as an ISO 8601 duration in hours, minutes and
+// seconds, like "PT1H2M3.5S". Negative durations are not supported.
+func formatISODuration(d time.Duration) string {
+	if d <= 0 {
+		return "PT0S"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("PT")
+
+	if h := d / time.Hour; h > 0 {
+		sb.WriteString(strconv.FormatInt(int64(h), 10))
+		sb.WriteByte('H')
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		sb.WriteString(strconv.FormatInt(int64(m), 10))
+		sb.WriteByte('M')
+		d -= m * time.Minute
+	}
+	if d > 0 {
+		sb.WriteString(strconv.FormatInt(int64(d/time.Second), 10))
+		if ns := d % time.Second; ns > 0 {
+			sb.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", ns), "0"))
+		}
+		sb.WriteByte('S')
+	}
+
+	return sb.String()
+}
+
+// parseISODuration parses an ISO 8601 duration of the form
+// P[nW][nD][T[nH][nM][nS]], where the last number may have a fraction.
+// Years and months are rejected, as their length is not fixed.
+func parseISODuration(s string) (time.Duration, error) {
+	invalid := func() (time.Duration, error) {
+		return 0, fmt.Errorf("gemist: invalid ISO 8601 duration %q", s)
+	}
+
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return invalid()
+	}
+
+	var (
+		d       time.Duration
+		inTime  bool
+		last    byte // last designator, to enforce their order
+		frac    bool // whether a fraction was seen, which must be last
+		nFields int
+	)
+
+	order := func(c byte) int { return strings.IndexByte("WDTHMS", c) }
+
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime || rest == "T" {
+				return invalid()
+			}
+			inTime, last = true, 'T'
+			rest = rest[1:]
+			continue
+		}
+
+		if frac {
+			return invalid()
+		}
+
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.' || rest[i] == ',') {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return invalid()
+		}
+
+		num := strings.ReplaceAll(rest[:i], ",", ".")
+		c := rest[i]
+		rest = rest[i+1:]
+
+		var unit time.Duration
+		switch {
+		case !inTime && c == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && c == 'D':
+			unit = 24 * time.Hour
+		case inTime && c == 'H':
+			unit = time.Hour
+		case inTime && c == 'M':
+			unit = time.Minute
+		case inTime && c == 'S':
+			unit = time.Second
+		default:
+			return invalid()
+		}
+
+		if last != 0 && order(c) <= order(last) {
+			return invalid()
+		}
+		last = c
+
+		ip, fp, dot := strings.Cut(num, ".")
+		if ip == "" || dot && fp == "" || strings.Contains(fp, ".") {
+			return invalid()
+		}
+
+		n, err := strconv.ParseInt(ip, 10, 64)
+		if err != nil || n > (math.MaxInt64-int64(d))/int64(unit) {
+			return invalid()
+		}
+		d += time.Duration(n) * unit
+
+		if dot {
+			f, _ := strconv.ParseFloat("0."+fp, 64)
+			d += time.Duration(math.Round(f * float64(unit)))
+			if d < 0 {
+				return invalid()
+			}
+			frac = true
+		}
+
+		nFields++
+	}
+
+	if nFields == 0 || last == 'T' {
+		return invalid()
+	}
+
+	return d, nil
+}
@@ -0,0 +1,177 @@
+package gemist
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBroadcastLength(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"14:45", 14*time.Minute + 45*time.Second},
+		{" 25:02", 25*time.Minute + 2*time.Second},
+		{"\n 50:00\n", 50 * time.Minute},
+		{"00:59", 59 * time.Second},
+		{"125:00", 125 * time.Minute},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"12:00:00", 12 * time.Hour},
+		{"camera 1:30:00 hd", 90 * time.Minute},
+		{"PT50M0S", 50 * time.Minute},
+		{"PT35M0S", 35 * time.Minute},
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+		{"35 min", 35 * time.Minute},
+		{"35 min.", 35 * time.Minute},
+		{"35min", 35 * time.Minute},
+		{"1 uur 5 min", time.Hour + 5*time.Minute},
+		{"2 uur", 2 * time.Hour},
+		{"1u 5m", time.Hour + 5*time.Minute},
+		{"90 sec", 90 * time.Second},
+		{"3 minuten 20 seconden", 3*time.Minute + 20*time.Second},
+		{"1 Hour 10 Minutes", time.Hour + 10*time.Minute},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBroadcastLength(tt.in)
+		if assert.NoError(t, err, "%q", tt.in) {
+			assert.Equal(t, tt.want, got, "%q", tt.in)
+		}
+	}
+}
+
+func TestParseBroadcastLength_invalid(t *testing.T) {
+	for _, s := range []string{
+		"", " ", "abc", "12", "1:2", "12:60", "1:60:00", "1:00:60", "12:34:56:78",
+		"P", "PT", "P1Y", "min", "5 weken", "uur 5", "99999:00",
+		"1:02:03:", ":12:34",
+	} {
+		_, err := parseBroadcastLength(s)
+		assert.Error(t, err, "%q", s)
+	}
+}
+
+func FuzzParseBroadcastLength(f *testing.F) {
+	for _, s := range []string{
+		"14:45", " 25:02", "1:02:03", "PT50M0S", "35 min", "1 uur 5 min",
+		"", "12:60", "P1DT2H", "PT0,5S", "9999:59:59",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		d, err := parseBroadcastLength(s)
+		if err == nil && d < 0 {
+			t.Errorf("parseBroadcastLength(%q) = %v, want non-negative", s, d)
+		}
+	})
+}
+
+func FuzzParseBroadcastLength_clock(f *testing.F) {
+	f.Add(uint32(0))
+	f.Add(uint32(59))
+	f.Add(uint32(3600))
+	f.Add(uint32(86399))
+
+	f.Fuzz(func(t *testing.T, sec uint32) {
+		sec %= 10000 * 3600
+		want := time.Duration(sec) * time.Second
+
+		s := fmt.Sprintf("%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+		d, err := parseBroadcastLength(s)
+		if err != nil || d != want {
+			t.Errorf("parseBroadcastLength(%q) = %v, %v, want %v", s, d, err, want)
+		}
+
+		if sec < 1000*60 {
+			s = fmt.Sprintf("%02d:%02d", sec/60, sec%60)
+			if d, err := parseBroadcastLength(s); err != nil || d != want {
+				t.Errorf("parseBroadcastLength(%q) = %v, %v, want %v", s, d, err, want)
+			}
+		}
+	})
+}
+
+func FuzzParseISODuration(f *testing.F) {
+	for _, s := range []string{"PT0S", "PT50M0S", "P1DT12H", "PT1.5S", "PT0,5H", "P1W", "PT9999999999999999999H"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		d, err := parseISODuration(s)
+		if err != nil {
+			return
+		}
+		if d < 0 {
+			t.Fatalf("parseISODuration(%q) = %v, want non-negative", s, d)
+		}
+		if !strings.HasPrefix(s, "P") {
+			t.Fatalf("parseISODuration(%q) accepted a string without P", s)
+		}
+
+		// Formatting is exact, so parsing the formatted duration
+		// gives the same duration.
+		f := formatISODuration(d)
+		if got, err := parseISODuration(f); err != nil || got != d {
+			t.Fatalf("parseISODuration(%q) = %v, %v, want %v", f, got, err, d)
+		}
+	})
+}
+
+func TestFormatISODuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                             "PT0S",
+		time.Second:                   "PT1S",
+		50 * time.Minute:              "PT50M",
+		26*time.Hour + 3*time.Second:  "PT26H3S",
+		time.Minute + time.Nanosecond: "PT1M0.000000001S",
+		time.Hour + 2*time.Minute + 250*time.Millisecond: "PT1H2M0.25S",
+	} {
+		assert.Equal(t, want, formatISODuration(d))
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"PT0S":             0,
+		"PT50M0S":          50 * time.Minute,
+		"PT1H2M3S":         time.Hour + 2*time.Minute + 3*time.Second,
+		"PT1.5S":           1500 * time.Millisecond,
+		"PT0,5H":           30 * time.Minute,
+		"P1D":              24 * time.Hour,
+		"P1W":              7 * 24 * time.Hour,
+		"P1DT12H":          36 * time.Hour,
+		"PT1M0.000000001S": time.Minute + time.Nanosecond,
+	} {
+		d, err := parseISODuration(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, want, d, s)
+		}
+	}
+
+	for _, s := range []string{
+		"", "P", "PT", "P1DT", "1H", "PT1H2H", "PT1S2M", "P1Y", "P1M", "PT1D",
+		"PT1.5M2S", "PT.5S", "PT1.S", "PT1.2.3S", "PTS", "PT-1S", "PT1",
+		"PT9999999999999999999H",
+	} {
+		_, err := parseISODuration(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestISODuration_roundTrip(t *testing.T) {
+	for _, d := range []time.Duration{
+		0, 1, time.Second - 1, time.Hour, 1<<63 - 1,
+		25*time.Minute + 1500*time.Millisecond,
+	} {
+		got, err := parseISODuration(formatISODuration(d))
+		if assert.NoError(t, err) {
+			assert.Equal(t, d, got)
+		}
+	}
+}
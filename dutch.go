@@ -0,0 +1,443 @@
+package gemist
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Schedule is the schedule of a broadcast as described in Dutch text on
+// pages, like "Elke zaterdagavond om half 7 op Radio 1" or "Za 6 okt 2007
+// 18:32".
+type Schedule struct {
+	// Recurring reports whether the text describes a recurring schedule,
+	// like "elke zaterdag".
+	Recurring bool
+
+	// Weekday is the day of the week, valid if HasWeekday is true.
+	Weekday    time.Weekday
+	HasWeekday bool
+
+	// Date is the date, including the time if HasTime is true, or the zero
+	// time if the text has no date.
+	Date time.Time
+
+	// Time is the time of day since midnight, valid if HasTime is true.
+	Time    time.Duration
+	HasTime bool
+
+	// Channel is the channel the broadcast is on, like "Radio 1", if the
+	// text names one.
+	Channel string
+}
+
+// ParseSchedule parses a Dutch schedule text. Relative dates like "gisteren"
+// and dates without a year are resolved against now. Words that are not part
+// of a schedule are ignored; an error is returned if the text contains no
+// schedule at all or a date or time is out of range.
+func ParseSchedule(s string, now time.Time) (Schedule, error) {
+	d, err := scanDutch(s)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	sc := Schedule{
+		Recurring:  d.recurring,
+		Weekday:    d.weekday,
+		HasWeekday: d.hasWeekday,
+		HasTime:    d.hasTime,
+		Channel:    d.channel,
+	}
+	if d.hasTime {
+		sc.Time = time.Duration(d.hour)*time.Hour + time.Duration(d.min)*time.Minute
+	}
+
+	if d.hasDate() {
+		if sc.Date, err = d.date(s, now); err != nil {
+			return Schedule{}, err
+		}
+		if !sc.HasWeekday {
+			sc.Weekday, sc.HasWeekday = sc.Date.Weekday(), true
+		}
+	}
+
+	if !sc.Recurring && !sc.HasWeekday && !sc.HasTime && sc.Channel == "" {
+		return Schedule{}, fmt.Errorf("gemist: no schedule in %q", s)
+	}
+
+	return sc, nil
+}
+
+// ParseDutchDate parses a Dutch date with an optional time, like "Za 6 okt
+// 2007 18:32", "zaterdag 6 oktober om half 7 's avonds" or "gisteren 18:32".
+// Relative dates and dates without a year are resolved against now; a date
+// without a year is taken to be in the past. Dates are in the time zone of
+// Amsterdam. Unlike ParseSchedule, an error is returned for any word that is
+// not part of a date.
+func ParseDutchDate(s string, now time.Time) (time.Time, error) {
+	d, err := scanDutch(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(d.unknown) > 0 {
+		return time.Time{}, fmt.Errorf("gemist: unknown word %q in date %q", d.unknown[0], s)
+	}
+	if !d.hasDate() {
+		return time.Time{}, fmt.Errorf("gemist: no date in %q", s)
+	}
+	return d.date(s, now)
+}
+
+// Schedule parses the subtitle of the broadcast as a schedule, resolving
+// relative dates against the date of the broadcast.
+func (bp *BroadcastProxy) Schedule() (Schedule, error) {
+	now := bp.Date
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return ParseSchedule(bp.SubTitle, now)
+}
+
+// dayPart is a part of the day, which makes times like "half 7" unambiguous.
+type dayPart int
+
+const (
+	noDayPart dayPart = iota
+	morning
+	afternoon
+	evening
+	night
+)
+
+var dayParts = map[string]dayPart{
+	"ochtend": morning,
+	"morgen":  morning,
+	"middag":  afternoon,
+	"avond":   evening,
+	"nacht":   night,
+}
+
+var dutchWeekdays = map[string]time.Weekday{
+	"zondag":    time.Sunday,
+	"maandag":   time.Monday,
+	"dinsdag":   time.Tuesday,
+	"woensdag":  time.Wednesday,
+	"donderdag": time.Thursday,
+	"vrijdag":   time.Friday,
+	"zaterdag":  time.Saturday,
+	"zo":        time.Sunday,
+	"ma":        time.Monday,
+	"di":        time.Tuesday,
+	"wo":        time.Wednesday,
+	"do":        time.Thursday,
+	"vr":        time.Friday,
+	"za":        time.Saturday,
+}
+
+var dutchMonths = map[string]time.Month{
+	"januari":   time.January,
+	"februari":  time.February,
+	"maart":     time.March,
+	"april":     time.April,
+	"mei":       time.May,
+	"juni":      time.June,
+	"juli":      time.July,
+	"augustus":  time.August,
+	"september": time.September,
+	"oktober":   time.October,
+	"november":  time.November,
+	"december":  time.December,
+	"jan":       time.January,
+	"feb":       time.February,
+	"mrt":       time.March,
+	"apr":       time.April,
+	"jun":       time.June,
+	"jul":       time.July,
+	"aug":       time.August,
+	"sep":       time.September,
+	"sept":      time.September,
+	"okt":       time.October,
+	"nov":       time.November,
+	"dec":       time.December,
+}
+
+// relativeDays maps words for recent days to the number of days ago.
+var relativeDays = map[string]int{
+	"vandaag":     0,
+	"van":         0, // as in "vanavond"
+	"gisteren":    1,
+	"gister":      1, // as in "gisteravond"
+	"eergisteren": 2,
+}
+
+// dutchFillers are words that can be part of a date but carry no meaning.
+var dutchFillers = map[string]bool{
+	"om":        true,
+	"rond":      true,
+	"omstreeks": true,
+	"vanaf":     true,
+	"de":        true,
+	"van":       true,
+	"en":        true,
+	"'s":        true,
+	"’s":        true,
+}
+
+// dutchClockRegexp matches a time like 18:32, 18.32 or 18u32. Submatches are
+// the hours and minutes.
+var dutchClockRegexp = regexp.MustCompile(`^(\d{1,2})[:.u](\d{2})$`)
+
+// dutchDate is the result of scanning Dutch text for a date.
+type dutchDate struct {
+	recurring  bool
+	weekday    time.Weekday
+	hasWeekday bool
+	part       dayPart
+
+	daysAgo  int
+	relative bool
+	day      int
+	month    time.Month
+	year     int
+
+	hour, min int
+	hasTime   bool
+
+	channel string
+	unknown []string
+}
+
+func (d *dutchDate) hasDate() bool {
+	return d.relative || d.day > 0
+}
+
+// date returns the date d describes. s is the text it was scanned from.
+func (d *dutchDate) date(s string, now time.Time) (time.Time, error) {
+	hour, min := 0, 0
+	if d.hasTime {
+		hour, min = d.hour, d.min
+	}
+
+	now = now.In(pListItemDateLoc)
+	if d.relative {
+		y, m, day := now.Date()
+		return time.Date(y, m, day-d.daysAgo, hour, min, 0, 0, pListItemDateLoc), nil
+	}
+
+	year := d.year
+	if year == 0 {
+		year = now.Year()
+		if time.Date(year, d.month, d.day, hour, min, 0, 0, pListItemDateLoc).After(now.Add(24 * time.Hour)) {
+			year--
+		}
+	}
+
+	t := time.Date(year, d.month, d.day, hour, min, 0, 0, pListItemDateLoc)
+	if t.Day() != d.day {
+		return time.Time{}, fmt.Errorf("gemist: invalid date %q", s)
+	}
+	return t, nil
+}
+
+// scanDutch scans s for the parts of a date and a schedule. Words that are not
+// recognized are collected in unknown.
+func scanDutch(s string) (*dutchDate, error) {
+	var (
+		d     dutchDate
+		words = strings.Fields(s)
+		lower = make([]string, len(words))
+	)
+	for i, w := range words {
+		lower[i] = strings.ToLower(strings.TrimRight(w, ",;!"))
+	}
+
+	// next returns the word after i, or "" if there is none.
+	next := func(i int) string {
+		if i+1 < len(lower) {
+			return lower[i+1]
+		}
+		return ""
+	}
+
+	for i := 0; i < len(lower); i++ {
+		w := strings.TrimSuffix(lower[i], ".")
+
+		if w == "elke" || w == "iedere" || w == "elk" || w == "ieder" {
+			d.recurring = true
+			continue
+		}
+
+		if w == "op" {
+			if isDutchDateWord(strings.TrimSuffix(next(i), ".")) {
+				continue // as in "op zaterdag"
+			}
+			d.channel = strings.TrimRight(strings.Join(words[i+1:], " "), ".,;!")
+			break
+		}
+
+		if wd, part, ok := parseDutchWeekday(w); ok {
+			d.weekday, d.hasWeekday = wd, true
+			if part != noDayPart {
+				d.part = part
+			}
+			continue
+		}
+
+		if days, part, ok := parseRelativeDay(w); ok {
+			d.daysAgo, d.relative = days, true
+			if part != noDayPart {
+				d.part = part
+			}
+			continue
+		}
+
+		if part, ok := parseDayPart(w); ok {
+			d.part = part
+			continue
+		}
+
+		if dutchFillers[w] {
+			continue
+		}
+
+		if m := dutchClockRegexp.FindStringSubmatch(w); m != nil {
+			h, _ := strconv.Atoi(m[1])
+			min, _ := strconv.Atoi(m[2])
+			if h > 24 || min > 59 || h == 24 && min > 0 {
+				return nil, fmt.Errorf("gemist: invalid time %q in %q", words[i], s)
+			}
+			d.hour, d.min, d.hasTime = h%24, min, true
+			continue
+		}
+
+		switch w {
+		case "half":
+			// "half 7" is half an hour before 7.
+			h, ok := parseDutchHour(next(i))
+			if !ok {
+				return nil, fmt.Errorf("gemist: invalid time %q in %q", "half "+next(i), s)
+			}
+			d.hour, d.min, d.hasTime = (h+11)%12, 30, true
+			i++
+			continue
+		case "kwart":
+			dir := next(i)
+			if i+2 >= len(lower) || dir != "over" && dir != "voor" {
+				return nil, fmt.Errorf("gemist: invalid time %q in %q", "kwart "+dir, s)
+			}
+			h, ok := parseDutchHour(lower[i+2])
+			if !ok {
+				return nil, fmt.Errorf("gemist: invalid time %q in %q", "kwart "+dir+" "+lower[i+2], s)
+			}
+			if dir == "over" {
+				d.hour, d.min = h%12, 15
+			} else {
+				d.hour, d.min = (h+11)%12, 45
+			}
+			d.hasTime = true
+			i += 2
+			continue
+		}
+
+		if n, err := strconv.Atoi(w); err == nil {
+			if month, ok := dutchMonths[strings.TrimSuffix(next(i), ".")]; ok {
+				if n < 1 || n > 31 {
+					return nil, fmt.Errorf("gemist: invalid date %q", s)
+				}
+				d.day, d.month = n, month
+				i++
+				if y, err := strconv.Atoi(next(i)); err == nil && len(next(i)) == 4 {
+					d.year = y
+					i++
+				}
+				continue
+			}
+			if next(i) == "uur" {
+				if n > 24 {
+					return nil, fmt.Errorf("gemist: invalid time %q in %q", w+" uur", s)
+				}
+				d.hour, d.min, d.hasTime = n%24, 0, true
+				i++
+				continue
+			}
+		}
+
+		d.unknown = append(d.unknown, words[i])
+	}
+
+	if d.hasTime && d.hour < 12 {
+		switch d.part {
+		case afternoon, evening:
+			d.hour += 12
+		}
+	}
+
+	return &d, nil
+}
+
+// parseDutchWeekday parses a weekday, which can be abbreviated, plural or
+// followed by a part of the day, like "zaterdagavond".
+func parseDutchWeekday(w string) (time.Weekday, dayPart, bool) {
+	if wd, ok := dutchWeekdays[w]; ok {
+		return wd, noDayPart, true
+	}
+	for name, wd := range dutchWeekdays {
+		rest, ok := strings.CutPrefix(w, name)
+		if !ok || len(name) == 2 {
+			continue
+		}
+		if rest == "en" || rest == "s" {
+			return wd, noDayPart, true
+		}
+		if part, ok := dayParts[rest]; ok {
+			return wd, part, true
+		}
+	}
+	return 0, noDayPart, false
+}
+
+// parseRelativeDay parses a word for a recent day, which can be followed by a
+// part of the day, like "gisteravond".
+func parseRelativeDay(w string) (days int, part dayPart, ok bool) {
+	if days, ok := relativeDays[w]; ok && w != "van" && w != "gister" {
+		return days, noDayPart, true
+	}
+	for prefix, days := range relativeDays {
+		if rest, ok := strings.CutPrefix(w, prefix); ok {
+			if part, ok := dayParts[rest]; ok {
+				return days, part, true
+			}
+		}
+	}
+	return 0, noDayPart, false
+}
+
+// parseDayPart parses a part of the day, like "avond" or "avonds" as in
+// "'s avonds".
+func parseDayPart(w string) (dayPart, bool) {
+	part, ok := dayParts[strings.TrimSuffix(w, "s")]
+	return part, ok
+}
+
+// parseDutchHour parses the hour of a time like "half 7".
+func parseDutchHour(w string) (int, bool) {
+	h, err := strconv.Atoi(w)
+	if err != nil || h < 1 || h > 12 {
+		return 0, false
+	}
+	return h, true
+}
+
+// isDutchDateWord reports whether w starts a date.
+func isDutchDateWord(w string) bool {
+	if _, _, ok := parseDutchWeekday(w); ok {
+		return true
+	}
+	if _, _, ok := parseRelativeDay(w); ok {
+		return true
+	}
+	_, err := strconv.Atoi(w)
+	return err == nil
+}
@@ -0,0 +1,139 @@
+package gemist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDutchDate(t *testing.T) {
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	now := time.Date(2007, time.October, 8, 12, 0, 0, 0, l)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Za 6 okt 2007 18:32", time.Date(2007, time.October, 6, 18, 32, 0, 0, l)},
+		{"Zo 30 dec 2001 00:45", time.Date(2001, time.December, 30, 0, 45, 0, 0, l)},
+		{"zaterdag 6 oktober 2007 18.32", time.Date(2007, time.October, 6, 18, 32, 0, 0, l)},
+		{"Zaterdag 6 okt. om 18u32", time.Date(2007, time.October, 6, 18, 32, 0, 0, l)},
+		{"Zaterdagavond 6 okt om Half 7", time.Date(2007, time.October, 6, 18, 30, 0, 0, l)},
+		{"6 oktober om half 7 's avonds", time.Date(2007, time.October, 6, 18, 30, 0, 0, l)},
+		{"6 oktober om half 7", time.Date(2007, time.October, 6, 6, 30, 0, 0, l)},
+		{"1 jan om kwart over 3 's middags", time.Date(2007, time.January, 1, 15, 15, 0, 0, l)},
+		{"1 jan om kwart voor 12 's nachts", time.Date(2007, time.January, 1, 11, 45, 0, 0, l)},
+		{"29 sept", time.Date(2007, time.September, 29, 0, 0, 0, 0, l)},
+		{"9 okt 10:00", time.Date(2007, time.October, 9, 10, 0, 0, 0, l)},
+		{"10 okt", time.Date(2006, time.October, 10, 0, 0, 0, 0, l)},
+		{"24 december", time.Date(2006, time.December, 24, 0, 0, 0, 0, l)},
+		{"Vandaag 18:32", time.Date(2007, time.October, 8, 18, 32, 0, 0, l)},
+		{"gisteren om 7 uur", time.Date(2007, time.October, 7, 7, 0, 0, 0, l)},
+		{"gisteravond om 7 uur", time.Date(2007, time.October, 7, 19, 0, 0, 0, l)},
+		{"eergisteren", time.Date(2007, time.October, 6, 0, 0, 0, 0, l)},
+		{"op zaterdag 6 okt 2007 om 18:32 op Radio 1", time.Date(2007, time.October, 6, 18, 32, 0, 0, l)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDutchDate(tt.in, now)
+		if assert.NoError(t, err, "%q", tt.in) {
+			assert.True(t, tt.want.Equal(got), "%q: %v != %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"25 min",
+		"Radio 1",
+		"Elke zaterdagavond om Half 7 op Radio 1",
+		"NOS verslag EK finale dameshockey",
+		"31 sep 2007",
+		"0 okt",
+		"6 okt 25:00",
+		"6 okt om half 13",
+		"6 okt om kwart",
+	} {
+		_, err := ParseDutchDate(in, now)
+		assert.Error(t, err, "%q", in)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	now := time.Date(2007, time.October, 8, 12, 0, 0, 0, l)
+
+	tests := []struct {
+		in   string
+		want Schedule
+	}{
+		{"Elke zaterdagavond om Half 7 op Radio 1", Schedule{
+			Recurring:  true,
+			Weekday:    time.Saturday,
+			HasWeekday: true,
+			Time:       18*time.Hour + 30*time.Minute,
+			HasTime:    true,
+			Channel:    "Radio 1",
+		}},
+		{"Zaterdagavond 6 okt om Half 7 op Radio 1", Schedule{
+			Weekday:    time.Saturday,
+			HasWeekday: true,
+			Date:       time.Date(2007, time.October, 6, 18, 30, 0, 0, l),
+			Time:       18*time.Hour + 30*time.Minute,
+			HasTime:    true,
+			Channel:    "Radio 1",
+		}},
+		{"Iedere werkdag om 7 uur op NPO Radio 2.", Schedule{
+			Recurring: true,
+			Time:      7 * time.Hour,
+			HasTime:   true,
+			Channel:   "NPO Radio 2",
+		}},
+		{"Gisteren op Nederland 3", Schedule{
+			Weekday:    time.Sunday,
+			HasWeekday: true,
+			Date:       time.Date(2007, time.October, 7, 0, 0, 0, 0, l),
+			Channel:    "Nederland 3",
+		}},
+		{"Op zondag", Schedule{
+			Weekday:    time.Sunday,
+			HasWeekday: true,
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSchedule(tt.in, now)
+		if !assert.NoError(t, err, "%q", tt.in) {
+			continue
+		}
+		assert.True(t, tt.want.Date.Equal(got.Date), "%q: date %v != %v", tt.in, got.Date, tt.want.Date)
+		got.Date = tt.want.Date
+		assert.Equal(t, tt.want, got, "%q", tt.in)
+	}
+
+	for _, in := range []string{"", "NOS verslag EK finale dameshockey", "31 sep om 7 uur"} {
+		_, err := ParseSchedule(in, now)
+		assert.Error(t, err, "%q", in)
+	}
+}
+
+func TestBroadcastProxy_Schedule(t *testing.T) {
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	bp := BroadcastProxy{
+		SubTitle: "Gisteravond om half 7 op Radio 1",
+		Date:     time.Date(2007, time.October, 7, 18, 32, 0, 0, l),
+	}
+
+	s, err := bp.Schedule()
+	if assert.NoError(t, err) {
+		assert.True(t, time.Date(2007, time.October, 6, 18, 30, 0, 0, l).Equal(s.Date), "date not equal (%v)", s.Date)
+		assert.Equal(t, "Radio 1", s.Channel)
+	}
+}
@@ -0,0 +1,146 @@
+package gemist
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound is matched by errors for pages that do not exist, such as
+	// an unknown episode.
+	ErrNotFound = errors.New("gemist: not found")
+
+	// ErrUnavailable is matched by errors for pages that are no longer
+	// available, such as an expired episode.
+	ErrUnavailable = errors.New("gemist: no longer available")
+
+	// ErrLayout is matched by all parse errors. It indicates the page layout
+	// differs from what the parser expects.
+	ErrLayout = errors.New("gemist: unexpected page layout")
+
+	// ErrMissing is matched by parse errors for elements that are not found.
+	ErrMissing = errors.New("gemist: element missing")
+
+	// ErrInvalid is matched by parse errors for values that are found but
+	// cannot be converted.
+	ErrInvalid = errors.New("gemist: invalid value")
+
+	// ErrNoProgram is returned by FetchProgram for broadcasts whose page
+	// does not link to the program they belong to.
+	ErrNoProgram = errors.New("gemist: broadcast has no program")
+)
+
+// A ParseError is returned when a page cannot be parsed.
+type ParseError struct {
+	Field string // field being parsed, e.g. "title" or "date"
+	Path  string // xmlpath expression of the field
+	URL   string // URL of the page, if known
+	Value string // raw value, if converting it failed
+	Err   error  // ErrMissing, ErrInvalid or the conversion error
+}
+
+func missingError(field string, s *selector) *ParseError {
+	return &ParseError{Field: field, Path: s.expr, Err: ErrMissing}
+}
+
+func invalidError(field string, s *selector, value string, err error) *ParseError {
+	if err == nil {
+		err = ErrInvalid
+	}
+	return &ParseError{Field: field, Path: s.expr, Value: value, Err: err}
+}
+
+func (e *ParseError) Error() string {
+	s := "gemist: error parsing " + e.Field
+	if e.URL != "" {
+		s += " of " + e.URL
+	}
+	if e.Value != "" {
+		s += fmt.Sprintf(" from %q", e.Value)
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Is reports whether the error matches ErrLayout, or ErrInvalid for a value
+// that could not be converted.
+func (e *ParseError) Is(target error) bool {
+	switch target {
+	case ErrLayout:
+		return true
+	case ErrInvalid:
+		return !errors.Is(e.Err, ErrMissing)
+	}
+	return false
+}
+
+// withURL sets the URL of a parse error in err, if not set yet.
+func withURL(err error, url string) error {
+	var perr *ParseError
+	if errors.As(err, &perr) && perr.URL == "" {
+		perr.URL = url
+	}
+	return err
+}
+
+// An HTTPError is returned when a page is requested and the response has a
+// non-2xx status code. Errors that are not an HTTPError come from the network
+// or from parsing the page.
+type HTTPError struct {
+	StatusCode int
+	URL        string
+
+	// RetryAfter is the delay the server asked for in the Retry-After
+	// header, or zero if it did not.
+	RetryAfter time.Duration
+}
+
+func newHTTPError(r *http.Response) *HTTPError {
+	return &HTTPError{
+		StatusCode: r.StatusCode,
+		URL:        r.Request.URL.String(),
+		RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("gemist: %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is reports whether the error matches ErrNotFound (404) or ErrUnavailable
+// (410 and 451).
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusGone ||
+			e.StatusCode == http.StatusUnavailableForLegalReasons
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header value in either delay-seconds or
+// HTTP-date form, relative to now.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
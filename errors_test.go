@@ -0,0 +1,91 @@
+package gemist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetBroadcast_status(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		target     error
+		delay      time.Duration
+	}{
+		{http.StatusNotFound, "", ErrNotFound, 0},
+		{http.StatusGone, "", ErrUnavailable, 0},
+		{http.StatusServiceUnavailable, "120", nil, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(tt.status)
+			w.Write([]byte("<html><head><title>Pagina niet gevonden</title></head></html>"))
+		}))
+
+		c := Client{BaseURL: s.URL}
+		_, err := c.GetBroadcast(context.Background(), "/zembla/18-03-2007/VARA_101141965")
+		s.Close()
+
+		var herr *HTTPError
+		if !assert.True(t, errors.As(err, &herr), "status %d: error is not an HTTP error: %v", tt.status, err) {
+			continue
+		}
+		assert.Equal(t, tt.status, herr.StatusCode, "status code not equal")
+		assert.Equal(t, s.URL+"/zembla/18-03-2007/VARA_101141965", herr.URL, "URL not equal")
+		assert.Equal(t, tt.delay, herr.RetryAfter, "retry after not equal")
+		if tt.target != nil {
+			assert.ErrorIs(t, err, tt.target)
+		} else {
+			assert.False(t, errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnavailable), "status %d matches sentinel", tt.status)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, time.October, 21, 7, 28, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Wed, 21 Oct 2015 07:29:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 21 Oct 2015 07:27:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestParseError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseBroadcast(strings.NewReader(`<html><head><meta content="music.radio_station" name="og:type" /></head><body></body></html>`))
+
+	var perr *ParseError
+	if assert.True(errors.As(err, &perr), "error is not a parse error: %v", err) {
+		assert.Equal("title", perr.Field, "field not equal")
+		assert.Equal(pMITitle.expr, perr.Path, "path not equal")
+	}
+	assert.ErrorIs(err, ErrLayout)
+	assert.ErrorIs(err, ErrMissing)
+	assert.False(errors.Is(err, ErrInvalid), "missing element matches ErrInvalid")
+	assert.False(errors.Is(err, ErrNotFound), "parse error matches ErrNotFound")
+
+	_, cerr := strconv.Atoi("1:23")
+	err = withURL(invalidError("length", broadcastParserV.l, "1:23", cerr), "http://www.npo.nl/zembla/18-03-2007/VARA_101141965")
+	assert.ErrorIs(err, ErrLayout)
+	assert.ErrorIs(err, ErrInvalid)
+	assert.ErrorIs(err, strconv.ErrSyntax)
+	assert.EqualError(err, `gemist: error parsing length of http://www.npo.nl/zembla/18-03-2007/VARA_101141965 from "1:23": strconv.Atoi: parsing "1:23": invalid syntax`)
+
+	err = withURL(err, "http://www.npo.nl/")
+	assert.Equal("http://www.npo.nl/zembla/18-03-2007/VARA_101141965", err.(*ParseError).URL, "URL overwritten")
+}
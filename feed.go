@@ -0,0 +1,192 @@
+package gemist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A Feed is an RSS 2.0 podcast feed, with iTunes tags, of the audio
+// broadcasts of a program.
+type Feed struct {
+	Program    *Program
+	Broadcasts []*Broadcast // resolved; live and video broadcasts are skipped
+
+	// Language is the language code of the feed. If empty, "nl" is used.
+	Language string
+
+	// EnclosureLength returns the size in bytes of the media of a broadcast,
+	// for example from a HEAD request or a downloaded file. If nil, or if it
+	// returns a negative size, the size is given as 0.
+	EnclosureLength func(*Broadcast) int64
+}
+
+// WriteFeed writes a podcast feed of program p and its resolved broadcasts bs
+// to w.
+func WriteFeed(w io.Writer, p *Program, bs []*Broadcast) error {
+	f := Feed{Program: p, Broadcasts: bs}
+	return f.Write(w)
+}
+
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Language    string       `xml:"language"`
+	Image       *rssImage    `xml:"image,omitempty"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	Summary     string       `xml:"itunes:summary"`
+	ItunesImage *itunesImage `xml:"itunes:image,omitempty"`
+	Explicit    string       `xml:"itunes:explicit"`
+	Items       []rssItem    `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Duration    string       `xml:"itunes:duration"`
+	Summary     string       `xml:"itunes:summary"`
+	ItunesImage *itunesImage `xml:"itunes:image,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// Write writes the feed to w.
+func (f *Feed) Write(w io.Writer) error {
+	p := f.Program
+
+	lang := f.Language
+	if lang == "" {
+		lang = "nl"
+	}
+
+	ch := rssChannel{
+		Title:       p.Title,
+		Link:        p.URL,
+		Description: p.Description,
+		Language:    lang,
+		Author:      p.ID().Broadcaster(),
+		Summary:     p.Description,
+		Explicit:    "false",
+	}
+
+	if len(p.ImageURLs) > 0 {
+		ch.Image = &rssImage{URL: p.ImageURLs[0], Title: p.Title, Link: p.URL}
+		ch.ItunesImage = &itunesImage{Href: p.ImageURLs[0]}
+	}
+
+	for _, b := range f.Broadcasts {
+		if !b.Type.IsAudio() || b.Type.IsLive() {
+			continue
+		}
+		ch.Items = append(ch.Items, f.item(b))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(rssFeed{Version: "2.0", Itunes: itunesNS, Channel: ch}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (f *Feed) item(b *Broadcast) rssItem {
+	guid := string(b.ID())
+	if guid == "" {
+		guid = b.URL
+	}
+
+	var size int64
+	if f.EnclosureLength != nil {
+		if size = f.EnclosureLength(b); size < 0 {
+			size = 0
+		}
+	}
+
+	desc := b.LongDescription
+	if desc == "" {
+		desc = b.Description
+	}
+
+	it := rssItem{
+		Title:       b.Title,
+		Link:        b.URL,
+		Description: desc,
+		GUID:        rssGUID{Value: guid},
+		PubDate:     b.Date.Format(time.RFC1123Z),
+		Enclosure: rssEnclosure{
+			URL:    b.MediaURL,
+			Length: size,
+			Type:   mediaType(containerOf(b.MediaURL, "mp3")),
+		},
+		Duration: formatItunesDuration(b.Length),
+		Summary:  b.Description,
+	}
+
+	if len(b.ImageURLs) > 0 {
+		it.ItunesImage = &itunesImage{Href: b.ImageURLs[0]}
+	}
+
+	return it
+}
+
+var mediaTypes = map[string]string{
+	"mp3": "audio/mpeg",
+	"m4a": "audio/mp4",
+	"aac": "audio/aac",
+	"mp4": "video/mp4",
+	"ts":  "video/mp2t",
+}
+
+// mediaType returns the MIME type of a container.
+func mediaType(container string) string {
+	if t, ok := mediaTypes[container]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// formatItunesDuration formats d as H:MM:SS, as used by itunes:duration.
+func formatItunesDuration(d time.Duration) string {
+	sec := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
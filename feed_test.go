@@ -0,0 +1,101 @@
+package gemist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFeed(t *testing.T) {
+	p := &Program{
+		MediaItem: MediaItem{
+			Title:       "Radio Bergeijk",
+			Description: "Nieuws uit Bergeijk & omstreken.",
+			ImageURLs:   []string{"http://images.poms.omroep.nl/image/s620/c620x620/1.jpg"},
+			URL:         "http://www.npo.nl/radio-bergeijk/VPRO_1234567",
+		},
+	}
+
+	bs := []*Broadcast{
+		{
+			MediaItem: MediaItem{
+				Title:       "Aflevering 1",
+				Description: "Kort.",
+				ImageURLs:   []string{"http://images.poms.omroep.nl/image/2.jpg"},
+				URL:         "http://www.npo.nl/radio-bergeijk/10-01-2015/POMS_VPRO_396140",
+			},
+			LongDescription: "Een lange beschrijving.",
+			Date:            time.Date(2015, 1, 10, 9, 30, 0, 0, time.UTC),
+			Length:          time.Hour + 2*time.Minute + 3*time.Second,
+			Type:            Audio,
+			MediaURL:        "http://download.omroep.nl/vpro/radio-bergeijk-1.mp3",
+		},
+		{
+			MediaItem: MediaItem{Title: "Video", URL: "http://www.npo.nl/x/VPRO_1"},
+			Type:      Video,
+		},
+	}
+
+	f := Feed{
+		Program:         p,
+		Broadcasts:      bs,
+		EnclosureLength: func(*Broadcast) int64 { return 12345 },
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	out := buf.String()
+
+	// Output must be well-formed XML.
+	dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	assert.Contains(t, out, `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">`)
+	assert.Contains(t, out, "<title>Radio Bergeijk</title>")
+	assert.Contains(t, out, "<description>Nieuws uit Bergeijk &amp; omstreken.</description>")
+	assert.Contains(t, out, "<language>nl</language>")
+	assert.Contains(t, out, "<itunes:author>VPRO</itunes:author>")
+	assert.Contains(t, out, `<itunes:image href="http://images.poms.omroep.nl/image/s620/c620x620/1.jpg"></itunes:image>`)
+	assert.Contains(t, out, "<url>http://images.poms.omroep.nl/image/s620/c620x620/1.jpg</url>")
+
+	assert.Contains(t, out, "<title>Aflevering 1</title>")
+	assert.Contains(t, out, "<description>Een lange beschrijving.</description>")
+	assert.Contains(t, out, `<guid isPermaLink="false">POMS_VPRO_396140</guid>`)
+	assert.Contains(t, out, "<pubDate>Sat, 10 Jan 2015 09:30:00 +0000</pubDate>")
+	assert.Contains(t, out, `<enclosure url="http://download.omroep.nl/vpro/radio-bergeijk-1.mp3" length="12345" type="audio/mpeg"></enclosure>`)
+	assert.Contains(t, out, "<itunes:duration>1:02:03</itunes:duration>")
+	assert.Contains(t, out, `<itunes:image href="http://images.poms.omroep.nl/image/2.jpg"></itunes:image>`)
+
+	assert.NotContains(t, out, "<title>Video</title>")
+}
+
+func TestWriteFeed_noLength(t *testing.T) {
+	p := &Program{MediaItem: MediaItem{Title: "P", URL: "http://www.npo.nl/p/VPRO_1"}}
+	bs := []*Broadcast{{
+		MediaItem: MediaItem{Title: "B", URL: "http://www.npo.nl/p/VPRO_2"},
+		Type:      Audio,
+		MediaURL:  "http://download.omroep.nl/b.mp3",
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFeed(&buf, p, bs))
+	assert.Contains(t, buf.String(), `length="0" type="audio/mpeg"`)
+	assert.NotContains(t, buf.String(), "<image>")
+}
+
+func TestFormatItunesDuration(t *testing.T) {
+	assert.Equal(t, "0:00:00", formatItunesDuration(0))
+	assert.Equal(t, "0:25:01", formatItunesDuration(25*time.Minute+time.Second))
+	assert.Equal(t, "12:00:00", formatItunesDuration(12*time.Hour))
+}
@@ -0,0 +1,49 @@
+package gemist
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// A BroadcastFilter reports whether a broadcast proxy should be included.
+type BroadcastFilter func(*BroadcastProxy) bool
+
+// Between returns a filter that matches broadcasts aired at or after from and
+// before to. A zero from or to leaves that side of the range unbounded.
+func Between(from, to time.Time) BroadcastFilter {
+	return func(bp *BroadcastProxy) bool {
+		if !from.IsZero() && bp.Date.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !bp.Date.Before(to) {
+			return false
+		}
+		return true
+	}
+}
+
+// TitleContains returns a filter that matches broadcasts with a title
+// containing s, ignoring case.
+func TitleContains(s string) BroadcastFilter {
+	s = strings.ToLower(s)
+	return func(bp *BroadcastProxy) bool {
+		return strings.Contains(strings.ToLower(bp.Title), s)
+	}
+}
+
+// TitleMatches returns a filter that matches broadcasts with a title matching re.
+func TitleMatches(re *regexp.Regexp) BroadcastFilter {
+	return func(bp *BroadcastProxy) bool {
+		return re.MatchString(bp.Title)
+	}
+}
+
+func matchAll(bp *BroadcastProxy, filters []BroadcastFilter) bool {
+	for _, f := range filters {
+		if !f(bp) {
+			return false
+		}
+	}
+	return true
+}
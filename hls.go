@@ -0,0 +1,318 @@
+package gemist
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A MasterPlaylist is an HLS master playlist, listing the variants of a
+// stream.
+type MasterPlaylist struct {
+	Variants   []Variant
+	Renditions []Rendition
+}
+
+// A Variant is a rendition of a stream in an HLS master playlist
+// (EXT-X-STREAM-INF).
+type Variant struct {
+	URI              string // URL of the media playlist
+	Bandwidth        int    // peak bits per second
+	AverageBandwidth int    // zero if not listed
+	Width            int    // zero if not listed
+	Height           int    // zero if not listed
+	Codecs           []string
+	FrameRate        float64
+	Audio            string // group ID of the audio renditions
+	Subtitles        string // group ID of the subtitle renditions
+}
+
+// A Rendition is an alternative rendition in an HLS master playlist
+// (EXT-X-MEDIA), such as an audio track or subtitles.
+type Rendition struct {
+	Type       string // AUDIO, VIDEO, SUBTITLES or CLOSED-CAPTIONS
+	GroupID    string
+	Name       string
+	Language   string
+	URI        string // URL of the media playlist, empty if muxed
+	Default    bool
+	AutoSelect bool
+}
+
+// A MediaPlaylist is an HLS media playlist, listing the segments of a
+// variant.
+type MediaPlaylist struct {
+	TargetDuration time.Duration
+	MediaSequence  int // sequence number of the first segment
+	Segments       []MediaSegment
+	EndList        bool // no segments will be added
+}
+
+// A MediaSegment is a segment in an HLS media playlist.
+type MediaSegment struct {
+	URI      string
+	Duration time.Duration
+	Sequence int  // media sequence number
+	Key      *Key // nil if the segment is not encrypted
+}
+
+// A Key describes how HLS media segments are encrypted (EXT-X-KEY).
+type Key struct {
+	Method string // AES-128 or SAMPLE-AES
+	URI    string // URL of the key
+	IV     []byte // nil if the IV is the media sequence number
+}
+
+var errNotPlaylist = errors.New("gemist: not an HLS playlist")
+
+// ParseMediaPlaylist parses an HLS media playlist. URIs in the playlist are
+// resolved against base, the URL of the playlist.
+func ParseMediaPlaylist(r io.Reader, base string) (*MediaPlaylist, error) {
+	bu, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := playlistLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		p   MediaPlaylist
+		key *Key
+		dur time.Duration
+		seq int
+	)
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			p.Segments = append(p.Segments, MediaSegment{
+				URI:      resolveURI(bu, line),
+				Duration: dur,
+				Sequence: p.MediaSequence + seq,
+				Key:      key,
+			})
+			dur = 0
+			seq++
+			continue
+		}
+
+		tag, value := splitTag(line)
+		switch tag {
+		case "#EXT-X-STREAM-INF":
+			return nil, errors.New("gemist: HLS master playlist instead of media playlist")
+
+		case "#EXT-X-TARGETDURATION":
+			sec, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("gemist: invalid HLS target duration %q", value)
+			}
+			p.TargetDuration = time.Duration(sec) * time.Second
+
+		case "#EXT-X-MEDIA-SEQUENCE":
+			if p.MediaSequence, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("gemist: invalid HLS media sequence %q", value)
+			}
+
+		case "#EXTINF":
+			sec, _, _ := strings.Cut(value, ",")
+			f, err := strconv.ParseFloat(sec, 64)
+			if err != nil {
+				return nil, fmt.Errorf("gemist: invalid HLS segment duration %q", value)
+			}
+			dur = time.Duration(f * float64(time.Second))
+
+		case "#EXT-X-KEY":
+			if key, err = parseKey(bu, parseAttributes(value)); err != nil {
+				return nil, err
+			}
+
+		case "#EXT-X-ENDLIST":
+			p.EndList = true
+		}
+	}
+
+	return &p, nil
+}
+
+func parseKey(base *url.URL, attrs map[string]string) (*Key, error) {
+	method := attrs["METHOD"]
+	if method == "NONE" {
+		return nil, nil
+	}
+
+	k := Key{
+		Method: method,
+		URI:    resolveURI(base, attrs["URI"]),
+	}
+
+	if s, ok := attrs["IV"]; ok {
+		iv, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+		if err != nil || len(iv) != 16 {
+			return nil, fmt.Errorf("gemist: invalid HLS key IV %q", s)
+		}
+		k.IV = iv
+	}
+
+	return &k, nil
+}
+
+// ParseMasterPlaylist parses an HLS master playlist. URIs in the playlist are
+// resolved against base, the URL of the playlist.
+func ParseMasterPlaylist(r io.Reader, base string) (*MasterPlaylist, error) {
+	bu, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := playlistLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var p MasterPlaylist
+	for i := 0; i < len(lines); i++ {
+		tag, value := splitTag(lines[i])
+		switch tag {
+		case "#EXT-X-STREAM-INF":
+			if i+1 >= len(lines) || strings.HasPrefix(lines[i+1], "#") {
+				return nil, fmt.Errorf("gemist: HLS variant without URI at line %q", lines[i])
+			}
+			i++
+
+			v, err := parseVariant(parseAttributes(value))
+			if err != nil {
+				return nil, err
+			}
+			v.URI = resolveURI(bu, lines[i])
+			p.Variants = append(p.Variants, v)
+
+		case "#EXT-X-MEDIA":
+			attrs := parseAttributes(value)
+			rd := Rendition{
+				Type:       attrs["TYPE"],
+				GroupID:    attrs["GROUP-ID"],
+				Name:       attrs["NAME"],
+				Language:   attrs["LANGUAGE"],
+				Default:    attrs["DEFAULT"] == "YES",
+				AutoSelect: attrs["AUTOSELECT"] == "YES",
+			}
+			if uri := attrs["URI"]; uri != "" {
+				rd.URI = resolveURI(bu, uri)
+			}
+			p.Renditions = append(p.Renditions, rd)
+		}
+	}
+
+	return &p, nil
+}
+
+func parseVariant(attrs map[string]string) (v Variant, err error) {
+	if v.Bandwidth, err = strconv.Atoi(attrs["BANDWIDTH"]); err != nil {
+		return v, fmt.Errorf("gemist: invalid HLS variant bandwidth %q", attrs["BANDWIDTH"])
+	}
+
+	if s, ok := attrs["AVERAGE-BANDWIDTH"]; ok {
+		if v.AverageBandwidth, err = strconv.Atoi(s); err != nil {
+			return v, fmt.Errorf("gemist: invalid HLS variant average bandwidth %q", s)
+		}
+	}
+
+	if s, ok := attrs["RESOLUTION"]; ok {
+		w, h, found := strings.Cut(s, "x")
+		v.Width, err = strconv.Atoi(w)
+		if err == nil && found {
+			v.Height, err = strconv.Atoi(h)
+		}
+		if err != nil || !found {
+			return v, fmt.Errorf("gemist: invalid HLS variant resolution %q", s)
+		}
+	}
+
+	if s, ok := attrs["FRAME-RATE"]; ok {
+		if v.FrameRate, err = strconv.ParseFloat(s, 64); err != nil {
+			return v, fmt.Errorf("gemist: invalid HLS variant frame rate %q", s)
+		}
+	}
+
+	if s := attrs["CODECS"]; s != "" {
+		for _, c := range strings.Split(s, ",") {
+			v.Codecs = append(v.Codecs, strings.TrimSpace(c))
+		}
+	}
+
+	v.Audio = attrs["AUDIO"]
+	v.Subtitles = attrs["SUBTITLES"]
+	return v, nil
+}
+
+// playlistLines returns the non-empty lines of a playlist, checking it starts
+// with #EXTM3U.
+func playlistLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || strings.TrimPrefix(lines[0], "\ufeff") != "#EXTM3U" {
+		return nil, errNotPlaylist
+	}
+
+	return lines[1:], nil
+}
+
+// splitTag splits a playlist line into its tag and value.
+func splitTag(line string) (tag, value string) {
+	tag, value, _ = strings.Cut(line, ":")
+	return
+}
+
+// parseAttributes parses an HLS attribute list. Quoted values are unquoted.
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+
+	for s != "" {
+		var name string
+		name, s, _ = strings.Cut(s, "=")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+			s = strings.TrimPrefix(s, ",")
+		} else {
+			value, s, _ = strings.Cut(s, ",")
+		}
+
+		attrs[strings.TrimSpace(name)] = value
+	}
+
+	return attrs
+}
+
+func resolveURI(base *url.URL, uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(u).String()
+}
@@ -0,0 +1,87 @@
+package gemist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDataMasterPlaylist = `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="Nederlands",LANGUAGE="nl",DEFAULT=YES,AUTOSELECT=YES
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Nederlands (ondertiteling)",LANGUAGE="nl",URI="subs/nl.m3u8"
+#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=1400000,AVERAGE-BANDWIDTH=1200000,RESOLUTION=1024x576,CODECS="avc1.4d401f,mp4a.40.2",FRAME-RATE=25.000,AUDIO="aac",SUBTITLES="subs"
+VARA_101141965-video=1200000.m3u8
+#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=700000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2",AUDIO="aac",SUBTITLES="subs"
+VARA_101141965-video=600000.m3u8
+
+#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=128000,CODECS="mp4a.40.2",AUDIO="aac"
+http://e.omroep.nl/vara/audio/VARA_101141965-audio=128000.m3u8
+`
+
+func TestParseMasterPlaylist(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := ParseMasterPlaylist(strings.NewReader(testDataMasterPlaylist), "http://e.omroep.nl/vara/adaptive/VARA_101141965.m3u8?hdnts=exp=1445438400")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal([]Variant{
+		{
+			URI:              "http://e.omroep.nl/vara/adaptive/VARA_101141965-video=1200000.m3u8",
+			Bandwidth:        1400000,
+			AverageBandwidth: 1200000,
+			Width:            1024,
+			Height:           576,
+			Codecs:           []string{"avc1.4d401f", "mp4a.40.2"},
+			FrameRate:        25,
+			Audio:            "aac",
+			Subtitles:        "subs",
+		},
+		{
+			URI:       "http://e.omroep.nl/vara/adaptive/VARA_101141965-video=600000.m3u8",
+			Bandwidth: 700000,
+			Width:     640,
+			Height:    360,
+			Codecs:    []string{"avc1.4d401e", "mp4a.40.2"},
+			Audio:     "aac",
+			Subtitles: "subs",
+		},
+		{
+			URI:       "http://e.omroep.nl/vara/audio/VARA_101141965-audio=128000.m3u8",
+			Bandwidth: 128000,
+			Codecs:    []string{"mp4a.40.2"},
+			Audio:     "aac",
+		},
+	}, p.Variants, "variants not equal")
+
+	assert.Equal([]Rendition{
+		{Type: "AUDIO", GroupID: "aac", Name: "Nederlands", Language: "nl", Default: true, AutoSelect: true},
+		{Type: "SUBTITLES", GroupID: "subs", Name: "Nederlands (ondertiteling)", Language: "nl", URI: "http://e.omroep.nl/vara/adaptive/subs/nl.m3u8"},
+	}, p.Renditions, "renditions not equal")
+}
+
+func TestParseMasterPlaylist_invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"<html></html>",
+		"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=128000\n",
+		"#EXTM3U\n#EXT-X-STREAM-INF:RESOLUTION=640x360\nvideo.m3u8\n",
+		"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=700000,RESOLUTION=640\nvideo.m3u8\n",
+	}
+
+	for _, tt := range tests {
+		_, err := ParseMasterPlaylist(strings.NewReader(tt), "http://e.omroep.nl/")
+		assert.Error(t, err, "%q", tt)
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"BANDWIDTH": "700000",
+		"CODECS":    "avc1.4d401e,mp4a.40.2",
+		"AUDIO":     "aac",
+	}, parseAttributes(`BANDWIDTH=700000,CODECS="avc1.4d401e,mp4a.40.2",AUDIO="aac"`))
+}
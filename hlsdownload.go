@@ -0,0 +1,403 @@
+package gemist
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// StateSuffix is appended to the destination path of an HLS download for the
+// file that records its progress, so it can be resumed.
+const StateSuffix = ".state"
+
+// hlsState is the state of an HLS download, stored next to the partial file.
+type hlsState struct {
+	Playlist string `json:"playlist"` // URL of the media playlist, see stateKey
+	Segments int    `json:"segments"` // number of segments in the playlist
+	Written  int    `json:"written"`  // number of segments written
+	Bytes    int64  `json:"bytes"`    // size of the partial file after them
+}
+
+// DownloadHLS downloads the HLS stream at url to dst, concatenating its
+// segments into a single file. Streams with video are written as MPEG-TS.
+// Audio-only streams of AAC are written as ADTS, the AAC frames taken out of
+// their MPEG-TS packets or packed audio segments; other audio keeps the
+// container of its segments. If url is a master playlist, the variant is
+// selected with the policy of d.
+//
+// Segments are requested by d.Workers workers and retried d.Retries times.
+// AES-128 encrypted segments are decrypted. The file is written to dst with
+// PartSuffix appended and its progress is recorded in a file with StateSuffix
+// appended, from which an interrupted download is resumed if the same media
+// playlist is selected again. The query of its URL is ignored, as it holds an
+// access token that differs each time.
+func (d *Downloader) DownloadHLS(ctx context.Context, url, dst string) error {
+	p, purl, err := d.getMediaPlaylist(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	part, statePath := dst+PartSuffix, dst+PartSuffix+StateSuffix
+
+	key := stateKey(purl)
+	st := loadHLSState(statePath)
+	if st.Playlist != key || st.Segments != len(p.Segments) {
+		// The state is missing or of another stream or variant, so start
+		// over rather than mixing segments of different renditions.
+		st = hlsState{Playlist: key, Segments: len(p.Segments)}
+	}
+
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The state is saved after the segments are synced to the partial file,
+	// but a partial file that is still shorter than the state claims is not
+	// padded; the download starts over instead.
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < st.Bytes {
+		st = hlsState{Playlist: key, Segments: len(p.Segments)}
+	}
+
+	if err := f.Truncate(st.Bytes); err != nil {
+		return err
+	}
+	if _, err := f.Seek(st.Bytes, io.SeekStart); err != nil {
+		return err
+	}
+
+	h := hlsDownload{
+		d:    d,
+		c:    d.client(),
+		keys: make(map[string][]byte),
+	}
+
+	pw := d.newProgressWriter(f, st.Bytes, estimateSize(p, st))
+	err = h.fetch(ctx, p.Segments, st.Written, func(data []byte) error {
+		if _, err := pw.Write(data); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+
+		st.Written++
+		st.Bytes = pw.p.Bytes
+		pw.p.Total = estimateSize(p, st)
+		return saveHLSState(statePath, st)
+	})
+	if err != nil {
+		return err
+	}
+
+	pr := pw.progress()
+	pr.Total = pr.Bytes
+	if err := d.finish(f, part, dst, pr); err != nil {
+		return err
+	}
+
+	return os.Remove(statePath)
+}
+
+// getMediaPlaylist gets the media playlist at url, or the media playlist of
+// the variant selected with the policy of d if url is a master playlist. It
+// also returns the URL the media playlist was read from.
+func (d *Downloader) getMediaPlaylist(ctx context.Context, url string) (*MediaPlaylist, string, error) {
+	c := d.client()
+
+	body, base, err := c.getBytes(ctx, url)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if bytes.Contains(body, []byte("#EXT-X-STREAM-INF")) {
+		mp, err := ParseMasterPlaylist(bytes.NewReader(body), base)
+		if err != nil {
+			return nil, "", err
+		}
+
+		ss := make([]Stream, len(mp.Variants))
+		for i, v := range mp.Variants {
+			ss[i] = Stream{URL: v.URI, Protocol: ProtocolHLS, Bitrate: v.Bandwidth, Width: v.Width, Height: v.Height, Codecs: v.Codecs}
+		}
+
+		s, err := SelectStream(ss, d.Policy)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if body, base, err = c.getBytes(ctx, s.URL); err != nil {
+			return nil, "", err
+		}
+	}
+
+	p, err := ParseMediaPlaylist(bytes.NewReader(body), base)
+	return p, base, err
+}
+
+// stateKey returns the URL of a media playlist without its query, which
+// carries an access token that changes every time the stream is resolved.
+func stateKey(purl string) string {
+	u, err := url.Parse(purl)
+	if err != nil {
+		return purl
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
+
+// estimateSize estimates the size of the download from the average size of
+// the segments written so far, or returns -1 if nothing is written yet.
+func estimateSize(p *MediaPlaylist, st hlsState) int64 {
+	if st.Written == 0 {
+		return -1
+	}
+	return st.Bytes / int64(st.Written) * int64(len(p.Segments))
+}
+
+// hlsDownload fetches the segments of an HLS download.
+type hlsDownload struct {
+	d *Downloader
+	c *Client
+
+	mu   sync.Mutex
+	keys map[string][]byte // keys by URI
+}
+
+type segmentResult struct {
+	i    int
+	data []byte
+	err  error
+}
+
+// fetch fetches segments ss[start:] concurrently and calls write with their
+// data in playlist order. At most twice the number of workers segments are
+// held in memory.
+func (h *hlsDownload) fetch(ctx context.Context, ss []MediaSegment, start int, write func([]byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := h.d.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	var (
+		jobs    = make(chan int)
+		results = make(chan segmentResult)
+		window  = make(chan struct{}, 2*workers)
+		wg      sync.WaitGroup
+	)
+
+	go func() {
+		defer close(jobs)
+		for i := start; i < len(ss); i++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				data, err := h.fetchSegment(ctx, ss[i])
+				results <- segmentResult{i, data, err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		pending = make(map[int][]byte)
+		next    = start
+		err     error
+	)
+
+	for r := range results {
+		if err != nil {
+			continue // drain
+		}
+		if r.err != nil {
+			err = r.err
+			cancel()
+			continue
+		}
+
+		pending[r.i] = r.data
+		for data, ok := pending[next]; ok; data, ok = pending[next] {
+			delete(pending, next)
+			if err = write(data); err != nil {
+				cancel()
+				break
+			}
+			next++
+			<-window
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// fetchSegment gets and decrypts segment s, retrying on errors unless the
+// client retries requests itself. Audio-only segments are returned as ADTS.
+func (h *hlsDownload) fetchSegment(ctx context.Context, s MediaSegment) ([]byte, error) {
+	retries := h.d.Retries
+	switch {
+	case h.c.Retry != nil:
+		retries = 0
+	case retries == 0:
+		retries = 3
+	case retries < 0:
+		retries = 0
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var data []byte
+		data, _, err = h.c.getBytes(ctx, s.URI)
+		if err == nil {
+			if data, err = h.decrypt(ctx, s, data); err != nil {
+				return nil, err
+			}
+			if adts, ok := segmentADTS(data); ok {
+				data = adts
+			}
+			return data, nil
+		}
+		if ctx.Err() != nil || errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("gemist: segment %d: %w", s.Sequence, err)
+}
+
+// decrypt decrypts the data of segment s with its key.
+func (h *hlsDownload) decrypt(ctx context.Context, s MediaSegment, data []byte) ([]byte, error) {
+	if s.Key == nil {
+		return data, nil
+	}
+	if s.Key.Method != "AES-128" {
+		return nil, fmt.Errorf("gemist: unsupported HLS encryption %s", s.Key.Method)
+	}
+
+	key, err := h.key(ctx, s.Key.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := s.Key.IV
+	if iv == nil {
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(s.Sequence))
+	}
+
+	return decryptAES128(key, iv, data)
+}
+
+// key returns the key at uri, getting it once.
+func (h *hlsDownload) key(ctx context.Context, uri string) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if k, ok := h.keys[uri]; ok {
+		return k, nil
+	}
+
+	k, _, err := h.c.getBytes(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != 16 {
+		return nil, fmt.Errorf("gemist: invalid HLS key size %d", len(k))
+	}
+
+	h.keys[uri] = k
+	return k, nil
+}
+
+// decryptAES128 decrypts AES-128-CBC data with PKCS#7 padding.
+func decryptAES128(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("gemist: encrypted HLS segment is not a multiple of the block size")
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(out) {
+		return nil, errors.New("gemist: invalid padding in HLS segment")
+	}
+	return out[:len(out)-pad], nil
+}
+
+func loadHLSState(path string) hlsState {
+	var st hlsState
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return st
+	}
+	if json.Unmarshal(data, &st) != nil {
+		return hlsState{}
+	}
+	return st
+}
+
+func saveHLSState(path string, st hlsState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,316 @@
+package gemist
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHLSKey = []byte("0123456789abcdef")
+
+// hlsServer serves an HLS stream of n AES-128 encrypted segments and records
+// the segments requested. The first request for segment fail fails.
+type hlsServer struct {
+	*httptest.Server
+	n, fail int
+
+	mu        sync.Mutex
+	requested map[int]int
+}
+
+func newHLSServer(n, fail int) *hlsServer {
+	s := &hlsServer{n: n, fail: fail, requested: make(map[int]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func testHLSSegment(i int) []byte {
+	return bytes.Repeat([]byte{0x47, byte(i)}, 100+i)
+}
+
+func (s *hlsServer) serve(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/master.m3u8":
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=700000,RESOLUTION=640x360\nlow/index.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=1400000,RESOLUTION=1024x576\nhigh/index.m3u8\n")
+	case r.URL.Path == "/high/index.m3u8":
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-MEDIA-SEQUENCE:7\n#EXT-X-KEY:METHOD=AES-128,URI=\"/key\"\n")
+		for i := 0; i < s.n; i++ {
+			fmt.Fprintf(w, "#EXTINF:10.0,\nsegment-%d.ts\n", i)
+		}
+		fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+	case r.URL.Path == "/key":
+		w.Write(testHLSKey)
+	case strings.HasPrefix(r.URL.Path, "/high/segment-"):
+		var i int
+		fmt.Sscanf(r.URL.Path, "/high/segment-%d.ts", &i)
+
+		s.mu.Lock()
+		s.requested[i]++
+		first := s.requested[i] == 1
+		s.mu.Unlock()
+
+		if i == s.fail && first {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(encryptAES128(testHLSKey, sequenceIV(7+i), testHLSSegment(i)))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func sequenceIV(seq int) []byte {
+	iv := make([]byte, aes.BlockSize)
+	iv[15] = byte(seq)
+	return iv
+}
+
+func encryptAES128(key, iv, data []byte) []byte {
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+	return out
+}
+
+func TestDownloader_DownloadHLS(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newHLSServer(10, 3)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.ts")
+
+	d := Downloader{Workers: 3, Retries: 1}
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/master.m3u8", dst))
+
+	var want []byte
+	for i := 0; i < 10; i++ {
+		want = append(want, testHLSSegment(i)...)
+	}
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(want, data, "downloaded data not equal")
+	assert.Equal(2, s.requested[3], "failed segment not retried")
+
+	for _, p := range []string{dst + PartSuffix, dst + PartSuffix + StateSuffix} {
+		_, err = os.Stat(p)
+		assert.True(os.IsNotExist(err), "%s not removed", p)
+	}
+}
+
+func TestDownloader_DownloadHLS_resume(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newHLSServer(6, -1)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.ts")
+
+	done := append(testHLSSegment(0), testHLSSegment(1)...)
+	require.NoError(t, os.WriteFile(dst+PartSuffix, append(done, "garbage"...), 0644))
+	require.NoError(t, saveHLSState(dst+PartSuffix+StateSuffix, hlsState{
+		Playlist: s.URL + "/high/index.m3u8",
+		Segments: 6,
+		Written:  2,
+		Bytes:    int64(len(done)),
+	}))
+
+	d := Downloader{Policy: MaxHeight(576)}
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/high/index.m3u8", dst))
+
+	want := done
+	for i := 2; i < 6; i++ {
+		want = append(want, testHLSSegment(i)...)
+	}
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(want, data, "resumed data not equal")
+	assert.Equal(map[int]int{2: 1, 3: 1, 4: 1, 5: 1}, s.requested, "written segments requested again")
+}
+
+func TestDownloader_DownloadHLS_audio(t *testing.T) {
+	frames := [][]byte{testADTSFrame(300, 'a'), testADTSFrame(200, 'b'), testADTSFrame(100, 'c')}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/audio.m3u8" {
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:10\n")
+			for i := range frames {
+				fmt.Fprintf(w, "#EXTINF:10.0,\nsegment-%d.ts\n", i)
+			}
+			fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+			return
+		}
+		var i int
+		fmt.Sscanf(r.URL.Path, "/segment-%d.ts", &i)
+		w.Write(testTSSegment(frames[i], tsStreamAAC))
+	}))
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.aac")
+
+	var d Downloader
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/audio.m3u8", dst))
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.Join(frames, nil), data, "audio not written as ADTS")
+}
+
+func TestDownloader_DownloadHLS_clientRetry(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.m3u8" {
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10.0,\nsegment-0.ts\n#EXT-X-ENDLIST\n")
+			return
+		}
+		atomic.AddInt32(&n, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	// The segment is retried by the client only.
+	d := Downloader{
+		Client:  &Client{Retry: &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}},
+		Retries: 5,
+	}
+	err := d.DownloadHLS(context.Background(), s.URL+"/index.m3u8", filepath.Join(t.TempDir(), "VARA_101141965.ts"))
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&n))
+}
+
+func TestParseMediaPlaylist(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := ParseMediaPlaylist(strings.NewReader(`#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:1
+#EXTINF:10.000,
+segment-1.ts
+#EXT-X-KEY:METHOD=AES-128,URI="https://e.omroep.nl/key",IV=0x000102030405060708090a0b0c0d0e0f
+#EXTINF:9.5,
+http://e.omroep.nl/vara/segment-2.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4,
+segment-3.ts
+#EXT-X-ENDLIST
+`), "http://e.omroep.nl/vara/adaptive/index.m3u8")
+	if !assert.NoError(err) {
+		return
+	}
+
+	key := &Key{Method: "AES-128", URI: "https://e.omroep.nl/key", IV: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}}
+	assert.Equal(&MediaPlaylist{
+		TargetDuration: 10e9,
+		MediaSequence:  1,
+		Segments: []MediaSegment{
+			{URI: "http://e.omroep.nl/vara/adaptive/segment-1.ts", Duration: 10e9, Sequence: 1},
+			{URI: "http://e.omroep.nl/vara/segment-2.ts", Duration: 9.5e9, Sequence: 2, Key: key},
+			{URI: "http://e.omroep.nl/vara/adaptive/segment-3.ts", Duration: 4e9, Sequence: 3},
+		},
+		EndList: true,
+	}, p)
+
+	_, err = ParseMediaPlaylist(strings.NewReader(testDataMasterPlaylist), "http://e.omroep.nl/")
+	assert.Error(err, "master playlist parsed as media playlist")
+}
+
+func TestDownloader_DownloadHLS_resumeNewToken(t *testing.T) {
+	s := newHLSServer(4, -1)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.ts")
+
+	// The state of a download of the same variant with an earlier token.
+	done := testHLSSegment(0)
+	require.NoError(t, os.WriteFile(dst+PartSuffix, done, 0644))
+	require.NoError(t, saveHLSState(dst+PartSuffix+StateSuffix, hlsState{
+		Playlist: stateKey(s.URL + "/high/index.m3u8?hdnts=exp=1~hmac=0a"),
+		Segments: 4,
+		Written:  1,
+		Bytes:    int64(len(done)),
+	}))
+
+	d := Downloader{}
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/high/index.m3u8?hdnts=exp=2~hmac=0b", dst))
+
+	assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1}, s.requested, "download not resumed")
+}
+
+func TestDownloader_DownloadHLS_resumeShortPart(t *testing.T) {
+	s := newHLSServer(4, -1)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.ts")
+
+	// The state claims more than the partial file holds.
+	done := testHLSSegment(0)
+	require.NoError(t, os.WriteFile(dst+PartSuffix, done[:10], 0644))
+	require.NoError(t, saveHLSState(dst+PartSuffix+StateSuffix, hlsState{
+		Playlist: s.URL + "/high/index.m3u8",
+		Segments: 4,
+		Written:  1,
+		Bytes:    int64(len(done)),
+	}))
+
+	d := Downloader{}
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/high/index.m3u8", dst))
+
+	var want []byte
+	for i := 0; i < 4; i++ {
+		want = append(want, testHLSSegment(i)...)
+	}
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, want, data, "download not started over")
+}
+
+func TestDownloader_DownloadHLS_resumeOtherVariant(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newHLSServer(6, -1)
+	defer s.Close()
+
+	dst := filepath.Join(t.TempDir(), "VARA_101141965.ts")
+
+	// A partial download of another variant with the same number of segments.
+	require.NoError(t, os.WriteFile(dst+PartSuffix, []byte("low quality"), 0644))
+	require.NoError(t, saveHLSState(dst+PartSuffix+StateSuffix, hlsState{
+		Playlist: s.URL + "/low/index.m3u8",
+		Segments: 6,
+		Written:  2,
+		Bytes:    int64(len("low quality")),
+	}))
+
+	d := Downloader{}
+	require.NoError(t, d.DownloadHLS(context.Background(), s.URL+"/high/index.m3u8", dst))
+
+	var want []byte
+	for i := 0; i < 6; i++ {
+		want = append(want, testHLSSegment(i)...)
+	}
+
+	data, err := os.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(want, data, "download not started over")
+	assert.Equal(map[int]int{0: 1, 1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, s.requested)
+}
@@ -0,0 +1,399 @@
+package gemist
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JSONSchemaVersion is the version of the JSON representation of the types in
+// this package. It is written as "schema_version" in Broadcast and Program
+// documents, and documents with a newer version are rejected.
+//
+// Version 1 uses snake_case field names, RFC 3339 dates, ISO 8601 durations
+// (e.g. "PT1H2M3S") and broadcast types as text, like "audio" or
+// "video_segment" (see BroadcastType.MarshalText). Zero dates are
+// omitted. Dates keep their UTC offset, but not the name of their location.
+const JSONSchemaVersion = 1
+
+type mediaItemJSON struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	ImageURLs   []string `json:"image_urls"`
+	URL         string   `json:"url"`
+}
+
+func newMediaItemJSON(mi MediaItem) mediaItemJSON {
+	return mediaItemJSON(mi)
+}
+
+func (j mediaItemJSON) mediaItem() MediaItem {
+	return MediaItem(j)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (mi MediaItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newMediaItemJSON(mi))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (mi *MediaItem) UnmarshalJSON(b []byte) error {
+	var j mediaItemJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*mi = j.mediaItem()
+	return nil
+}
+
+type broadcastProxyJSON struct {
+	mediaItemJSON
+	SubTitle string       `json:"sub_title"`
+	Date     *time.Time   `json:"date,omitempty"`
+	Length   jsonDuration `json:"length"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bp BroadcastProxy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(broadcastProxyJSON{
+		mediaItemJSON: newMediaItemJSON(bp.MediaItem),
+		SubTitle:      bp.SubTitle,
+		Date:          jsonTime(bp.Date),
+		Length:        jsonDuration(bp.Length),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (bp *BroadcastProxy) UnmarshalJSON(b []byte) error {
+	var j broadcastProxyJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*bp = BroadcastProxy{
+		MediaItem: j.mediaItem(),
+		SubTitle:  j.SubTitle,
+		Date:      timeOf(j.Date),
+		Length:    time.Duration(j.Length),
+	}
+	return nil
+}
+
+type broadcastJSON struct {
+	Version int `json:"schema_version"`
+	mediaItemJSON
+	LongDescription string        `json:"long_description"`
+	Date            *time.Time    `json:"date,omitempty"`
+	Length          jsonDuration  `json:"length"`
+	Type            BroadcastType `json:"type"`
+	MediaURL        string        `json:"media_url"`
+	Segments        []*Segment    `json:"segments"`
+	Streams         []Stream      `json:"streams"`
+	Program         *ProgramRef   `json:"program,omitempty"`
+	Schema          *Schema       `json:"schema,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Broadcast) MarshalJSON() ([]byte, error) {
+	return json.Marshal(broadcastJSON{
+		Version:         JSONSchemaVersion,
+		mediaItemJSON:   newMediaItemJSON(b.MediaItem),
+		LongDescription: b.LongDescription,
+		Date:            jsonTime(b.Date),
+		Length:          jsonDuration(b.Length),
+		Type:            b.Type,
+		MediaURL:        b.MediaURL,
+		Segments:        b.Segments,
+		Streams:         b.Streams,
+		Program:         b.Program,
+		Schema:          b.Schema,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Broadcast) UnmarshalJSON(data []byte) error {
+	var j broadcastJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(j.Version); err != nil {
+		return err
+	}
+	*b = Broadcast{
+		MediaItem:       j.mediaItem(),
+		LongDescription: j.LongDescription,
+		Date:            timeOf(j.Date),
+		Length:          time.Duration(j.Length),
+		Type:            j.Type,
+		MediaURL:        j.MediaURL,
+		Segments:        j.Segments,
+		Streams:         j.Streams,
+		Program:         j.Program,
+		Schema:          j.Schema,
+	}
+	return nil
+}
+
+type programJSON struct {
+	Version int `json:"schema_version"`
+	mediaItemJSON
+	NumBroadcasts int               `json:"num_broadcasts"`
+	Broadcasts    []*BroadcastProxy `json:"broadcasts"`
+	NumSegments   int               `json:"num_segments"`
+	Segments      []*Segment        `json:"segments"`
+	Schema        *Schema           `json:"schema,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Unlike the other fields of a
+// program, the broadcasts and segments listed on the program page are only
+// accessible with methods, but they are part of the JSON representation.
+func (p Program) MarshalJSON() ([]byte, error) {
+	return json.Marshal(programJSON{
+		Version:       JSONSchemaVersion,
+		mediaItemJSON: newMediaItemJSON(p.MediaItem),
+		NumBroadcasts: p.nbs,
+		Broadcasts:    p.bs,
+		NumSegments:   p.nss,
+		Segments:      p.ss,
+		Schema:        p.Schema,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Program) UnmarshalJSON(b []byte) error {
+	var j programJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(j.Version); err != nil {
+		return err
+	}
+	*p = Program{
+		MediaItem: j.mediaItem(),
+		Schema:    j.Schema,
+		bs:        j.Broadcasts,
+		nbs:       j.NumBroadcasts,
+		ss:        j.Segments,
+		nss:       j.NumSegments,
+	}
+	return nil
+}
+
+type segmentJSON struct {
+	mediaItemJSON
+	Length    jsonDuration `json:"length"`
+	ParentID  MediaID      `json:"parent_id"`
+	ParentURL string       `json:"parent_url"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Segment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(segmentJSON{
+		mediaItemJSON: newMediaItemJSON(s.MediaItem),
+		Length:        jsonDuration(s.Length),
+		ParentID:      s.ParentID,
+		ParentURL:     s.ParentURL,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Segment) UnmarshalJSON(b []byte) error {
+	var j segmentJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = Segment{
+		MediaItem: j.mediaItem(),
+		Length:    time.Duration(j.Length),
+		ParentID:  j.ParentID,
+		ParentURL: j.ParentURL,
+	}
+	return nil
+}
+
+type streamJSON struct {
+	URL       string     `json:"url"`
+	Protocol  Protocol   `json:"protocol"`
+	Container string     `json:"container"`
+	Bitrate   int        `json:"bitrate"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Codecs    []string   `json:"codecs"`
+	Expires   *time.Time `json:"expires,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Stream) MarshalJSON() ([]byte, error) {
+	return json.Marshal(streamJSON{
+		URL:       s.URL,
+		Protocol:  s.Protocol,
+		Container: s.Container,
+		Bitrate:   s.Bitrate,
+		Width:     s.Width,
+		Height:    s.Height,
+		Codecs:    s.Codecs,
+		Expires:   jsonTime(s.Expires),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Stream) UnmarshalJSON(b []byte) error {
+	var j streamJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = Stream{
+		URL:       j.URL,
+		Protocol:  j.Protocol,
+		Container: j.Container,
+		Bitrate:   j.Bitrate,
+		Width:     j.Width,
+		Height:    j.Height,
+		Codecs:    j.Codecs,
+		Expires:   timeOf(j.Expires),
+	}
+	return nil
+}
+
+type programRefJSON struct {
+	ID    MediaID `json:"id"`
+	Title string  `json:"title"`
+	URL   string  `json:"url"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r ProgramRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(programRefJSON(r))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *ProgramRef) UnmarshalJSON(b []byte) error {
+	var j programRefJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*r = ProgramRef(j)
+	return nil
+}
+
+type schemaJSON struct {
+	Items       []*SchemaItem `json:"items"`
+	Breadcrumbs []Breadcrumb  `json:"breadcrumbs"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(schemaJSON(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var j schemaJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = Schema(j)
+	return nil
+}
+
+type schemaItemJSON struct {
+	Type         string       `json:"type"`
+	Name         string       `json:"name,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	URL          string       `json:"url,omitempty"`
+	ThumbnailURL string       `json:"thumbnail_url,omitempty"`
+	ContentURL   string       `json:"content_url,omitempty"`
+	Duration     jsonDuration `json:"duration"`
+	StartDate    *time.Time   `json:"start_date,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (si SchemaItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(schemaItemJSON{
+		Type:         si.Type,
+		Name:         si.Name,
+		Description:  si.Description,
+		URL:          si.URL,
+		ThumbnailURL: si.ThumbnailURL,
+		ContentURL:   si.ContentURL,
+		Duration:     jsonDuration(si.Duration),
+		StartDate:    jsonTime(si.StartDate),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (si *SchemaItem) UnmarshalJSON(b []byte) error {
+	var j schemaItemJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*si = SchemaItem{
+		Type:         j.Type,
+		Name:         j.Name,
+		Description:  j.Description,
+		URL:          j.URL,
+		ThumbnailURL: j.ThumbnailURL,
+		ContentURL:   j.ContentURL,
+		Duration:     time.Duration(j.Duration),
+		StartDate:    timeOf(j.StartDate),
+	}
+	return nil
+}
+
+type breadcrumbJSON struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Breadcrumb) MarshalJSON() ([]byte, error) {
+	return json.Marshal(breadcrumbJSON(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Breadcrumb) UnmarshalJSON(data []byte) error {
+	var j breadcrumbJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*b = Breadcrumb(j)
+	return nil
+}
+
+func checkSchemaVersion(v int) error {
+	if v > JSONSchemaVersion {
+		return fmt.Errorf("gemist: unsupported JSON schema version %d", v)
+	}
+	return nil
+}
+
+// jsonTime returns a pointer to t, or nil if t is zero.
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// timeOf returns the time t points to, or the zero time if t is nil.
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// jsonDuration is a duration that is represented as an ISO 8601 duration.
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalText() ([]byte, error) {
+	return []byte(formatISODuration(time.Duration(d))), nil
+}
+
+func (d *jsonDuration) UnmarshalText(b []byte) error {
+	v, err := parseISODuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(v)
+	return nil
+}
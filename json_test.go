@@ -0,0 +1,140 @@
+package gemist
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJSONBroadcast() *Broadcast {
+	loc := time.FixedZone("", 2*60*60)
+	return &Broadcast{
+		MediaItem: MediaItem{
+			Title:       "Het radiostation voor Bergeijk",
+			Description: "Radio Bergeijk",
+			ImageURLs:   []string{"http://images.poms.omroep.nl/image/1.jpg"},
+			URL:         "http://www.npo.nl/radio-bergeijk/05-06-2004/POMS_VPRO_397233",
+		},
+		LongDescription: "Een lange beschrijving.",
+		Date:            time.Date(2004, 6, 5, 13, 32, 0, 0, loc),
+		Length:          25*time.Minute + 1500*time.Millisecond,
+		Type:            Audio,
+		MediaURL:        "http://download.omroep.nl/vpro/bergeijk.mp3",
+		Segments: []*Segment{{
+			MediaItem: MediaItem{Title: "Fragment", URL: "http://www.npo.nl/x/POMS_VPRO_397233/POMS_VPRO_1"},
+			Length:    90 * time.Second,
+			ParentID:  "POMS_VPRO_397233",
+			ParentURL: "http://www.npo.nl/x/POMS_VPRO_397233",
+		}},
+		Streams: []Stream{{
+			URL:       "http://download.omroep.nl/vpro/bergeijk.mp3",
+			Protocol:  ProtocolHTTP,
+			Container: "mp3",
+			Bitrate:   128000,
+			Codecs:    []string{"mp3"},
+			Expires:   time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		Program: &ProgramRef{
+			ID:    "POMS_S_VPRO_396280",
+			Title: "Radio Bergeijk",
+			URL:   "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280",
+		},
+		Schema: &Schema{
+			Items: []*SchemaItem{
+				{Type: "http://schema.org/TVEpisode", Name: "Radio bergeijk", URL: "http://www.npo.nl/radio-bergeijk/05-06-2004/POMS_VPRO_397233"},
+				{Type: "http://schema.org/BroadcastEvent", StartDate: time.Date(2004, 6, 5, 11, 32, 0, 0, time.UTC)},
+				{Type: "http://schema.org/AudioObject", Duration: 25 * time.Minute},
+			},
+			Breadcrumbs: []Breadcrumb{{Title: "Radio Bergeijk", URL: "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280"}},
+		},
+	}
+}
+
+func TestBroadcast_MarshalJSON(t *testing.T) {
+	b := testJSONBroadcast()
+
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, float64(JSONSchemaVersion), m["schema_version"])
+	assert.Equal(t, "Het radiostation voor Bergeijk", m["title"])
+	assert.Equal(t, "Een lange beschrijving.", m["long_description"])
+	assert.Equal(t, "2004-06-05T13:32:00+02:00", m["date"])
+	assert.Equal(t, "PT25M1.5S", m["length"])
+	assert.Equal(t, "audio", m["type"])
+	assert.Equal(t, "http://download.omroep.nl/vpro/bergeijk.mp3", m["media_url"])
+	assert.Len(t, m["image_urls"], 1)
+
+	seg := m["segments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "PT1M30S", seg["length"])
+	assert.Equal(t, "POMS_VPRO_397233", seg["parent_id"])
+
+	st := m["streams"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "http", st["protocol"])
+	assert.Equal(t, "2016-01-01T00:00:00Z", st["expires"])
+
+	assert.Equal(t, "POMS_S_VPRO_396280", m["program"].(map[string]interface{})["id"])
+
+	sc := m["schema"].(map[string]interface{})
+	items := sc["items"].([]interface{})
+	assert.Len(t, items, 3)
+	assert.Equal(t, "2004-06-05T11:32:00Z", items[1].(map[string]interface{})["start_date"])
+	assert.Equal(t, "PT25M", items[2].(map[string]interface{})["duration"])
+	assert.Equal(t, "Radio Bergeijk", sc["breadcrumbs"].([]interface{})[0].(map[string]interface{})["title"])
+
+	var got Broadcast
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.True(t, b.Date.Equal(got.Date))
+	got.Date = b.Date
+	assert.Equal(t, *b, got)
+}
+
+func TestBroadcast_UnmarshalJSON(t *testing.T) {
+	var b Broadcast
+	assert.Error(t, json.Unmarshal([]byte(`{"schema_version":2}`), &b))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"radio"}`), &b))
+	assert.Error(t, json.Unmarshal([]byte(`{"length":"25 min"}`), &b))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"title":"T","type":"video","length":"PT1H"}`), &b))
+	assert.Equal(t, "T", b.Title)
+	assert.Equal(t, Video, b.Type)
+	assert.Equal(t, time.Hour, b.Length)
+	assert.True(t, b.Date.IsZero())
+}
+
+func TestProgram_MarshalJSON(t *testing.T) {
+	p := &Program{
+		MediaItem: MediaItem{Title: "Radio Bergeijk", URL: "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280"},
+		bs: []*BroadcastProxy{{
+			MediaItem: MediaItem{Title: "Aflevering", ImageURLs: []string{"http://images.poms.omroep.nl/image/1.jpg"}},
+			SubTitle:  "Radio Bergeijk",
+			Date:      time.Date(2015, 1, 10, 9, 30, 0, 0, time.UTC),
+			Length:    time.Hour,
+		}},
+		nbs: 693,
+		ss:  []*Segment{{MediaItem: MediaItem{Title: "Fragment"}}},
+		nss: 139,
+	}
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, float64(693), m["num_broadcasts"])
+	assert.Equal(t, float64(139), m["num_segments"])
+	bp := m["broadcasts"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Radio Bergeijk", bp["sub_title"])
+	assert.Equal(t, "PT1H", bp["length"])
+	assert.Equal(t, "2015-01-10T09:30:00Z", bp["date"])
+
+	var got Program
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, *p, got)
+	assert.Equal(t, 693, got.NumBroadcasts())
+}
@@ -0,0 +1,90 @@
+package gemist
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+)
+
+// A MediaID is an NPO POMS identifier, such as POMS_VPRO_396139 for a
+// broadcast or POMS_S_VPRO_396280 for a series. Uitzending Gemist URLs end
+// in the identifier of the media item.
+type MediaID string
+
+// mediaIDRegexp matches media IDs. Submatches:
+// 1 -> prefix (POMS or WO), optional
+// 2 -> series marker (S_), optional
+// 3 -> broadcaster
+// 4 -> number
+var mediaIDRegexp = regexp.MustCompile(`^(?:(POMS|WO)_)?(S_)?([A-Z][A-Z0-9]*)_(\d+)$`)
+
+// ParseMediaID parses and validates a media ID.
+func ParseMediaID(s string) (MediaID, error) {
+	if !mediaIDRegexp.MatchString(s) {
+		return "", fmt.Errorf("gemist: invalid media ID %q", s)
+	}
+
+	return MediaID(s), nil
+}
+
+// MediaIDFromURL returns the media ID at the end of the path of rawurl.
+func MediaIDFromURL(rawurl string) (MediaID, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+
+	return ParseMediaID(path.Base(u.Path))
+}
+
+// Valid reports whether id is a well-formed media ID.
+func (id MediaID) Valid() bool {
+	return mediaIDRegexp.MatchString(string(id))
+}
+
+// Broadcaster returns the broadcaster prefix of id, such as VPRO or VARA, or
+// an empty string if id is invalid.
+func (id MediaID) Broadcaster() string {
+	parts := mediaIDRegexp.FindStringSubmatch(string(id))
+	if parts == nil {
+		return ""
+	}
+	return parts[3]
+}
+
+// IsSeries reports whether id identifies a series (program) instead of an
+// episode or segment.
+func (id MediaID) IsSeries() bool {
+	parts := mediaIDRegexp.FindStringSubmatch(string(id))
+	return parts != nil && parts[2] != ""
+}
+
+// URL returns the Uitzending Gemist URL of id, which is redirected to the
+// page of the media item.
+func (id MediaID) URL() string {
+	return DefaultBaseURL + "/" + string(id)
+}
+
+func (id MediaID) String() string {
+	return string(id)
+}
+
+// ID returns the media ID in the URL of the media item, or an empty MediaID
+// if the URL does not end in one.
+func (mi MediaItem) ID() MediaID {
+	id, err := MediaIDFromURL(mi.URL)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// mediaPath returns the path of the page of s if s is a media ID, or s
+// unchanged otherwise.
+func mediaPath(s string) string {
+	if id, err := ParseMediaID(s); err == nil {
+		return "/" + string(id)
+	}
+	return s
+}
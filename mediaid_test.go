@@ -0,0 +1,65 @@
+package gemist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMediaID(t *testing.T) {
+	tests := []struct {
+		in          string
+		valid       bool
+		broadcaster string
+		series      bool
+	}{
+		{"POMS_VPRO_396139", true, "VPRO", false},
+		{"VARA_101141965", true, "VARA", false},
+		{"VPRO_1122739", true, "VPRO", false},
+		{"POMS_S_VPRO_396280", true, "VPRO", true},
+		{"POMS_S_VARA_099718", true, "VARA", true},
+		{"WO_VPRO_034432", true, "VPRO", false},
+		{"KN_1676390", true, "KN", false},
+		{"", false, "", false},
+		{"POMS_VPRO", false, "", false},
+		{"radio-bergeijk", false, "", false},
+		{"POMS_VPRO_396139/POMS_VPRO_396140", false, "", false},
+		{"poms_vpro_396139", false, "", false},
+	}
+
+	for _, tt := range tests {
+		id, err := ParseMediaID(tt.in)
+		if !tt.valid {
+			assert.Error(t, err, tt.in)
+			assert.False(t, MediaID(tt.in).Valid(), tt.in)
+			continue
+		}
+
+		if assert.NoError(t, err, tt.in) {
+			assert.True(t, id.Valid(), tt.in)
+			assert.Equal(t, tt.broadcaster, id.Broadcaster(), "%s broadcaster not equal", tt.in)
+			assert.Equal(t, tt.series, id.IsSeries(), "%s series not equal", tt.in)
+		}
+	}
+}
+
+func TestMediaItem_ID(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Program{MediaItem: MediaItem{URL: "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280"}}
+	assert.Equal(MediaID("POMS_S_VPRO_396280"), p.ID())
+
+	s := Segment{MediaItem: MediaItem{URL: "http://www.npo.nl/radio-bergeijk/03-04-2001/POMS_VPRO_396139/POMS_VPRO_396140"}}
+	assert.Equal(MediaID("POMS_VPRO_396140"), s.ID())
+
+	assert.Equal(MediaID(""), MediaItem{URL: "http://www.npo.nl/a-z"}.ID())
+	assert.Equal("http://www.npo.nl/VARA_101141965", MediaID("VARA_101141965").URL())
+}
+
+func TestClient_pageURL_mediaID(t *testing.T) {
+	c := Client{BaseURL: "http://127.0.0.1:8080"}
+	u, err := c.pageURL("POMS_VPRO_396139")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://127.0.0.1:8080/POMS_VPRO_396139", u)
+	}
+}
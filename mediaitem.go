@@ -1,10 +1,6 @@
 package gemist
 
-import (
-	"errors"
-
-	"gopkg.in/xmlpath.v2"
-)
+import "gopkg.in/xmlpath.v2"
 
 // MediaItem represents a generic Uitzending Gemist media item.
 type MediaItem struct {
@@ -15,28 +11,39 @@ type MediaItem struct {
 }
 
 var (
-	pMITitle  = xmlpath.MustCompile("/html/head/meta[@name='og:title']/@content")
-	pMIDesc   = xmlpath.MustCompile("/html/head/meta[@name='og:description']/@content")
-	pMIURL    = xmlpath.MustCompile("/html/head/meta[@name='og:url']/@content")
-	pMIImages = xmlpath.MustCompile("/html/head/meta[@name='og:image']/@content")
+	pMITitle  = mustCompile("/html/head/meta[@name='og:title']/@content")
+	pMIDesc   = mustCompile("/html/head/meta[@name='og:description']/@content")
+	pMIURL    = mustCompile("/html/head/meta[@name='og:url']/@content")
+	pMIImages = mustCompile("/html/head/meta[@name='og:image']/@content")
 )
 
-func parseMediaItem(n *xmlpath.Node) (mi MediaItem, err error) {
+// parseMediaItem parses the media item of a page from its Open Graph tags.
+// Fields that are missing are taken from the schema items fallback, in order.
+func parseMediaItem(n *xmlpath.Node, fallback []*SchemaItem) (mi MediaItem, err error) {
 	title, ok := pMITitle.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing title of media item")
+		title, ok = schemaValue(fallback, func(si *SchemaItem) string { return si.Name })
+	}
+	if !ok {
+		err = missingError("title", pMITitle)
 		return
 	}
 
 	desc, ok := pMIDesc.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing description of media item")
+		desc, ok = schemaValue(fallback, func(si *SchemaItem) string { return si.Description })
+	}
+	if !ok {
+		err = missingError("description", pMIDesc)
 		return
 	}
 
 	url, ok := pMIURL.String(n)
 	if !ok {
-		err = errors.New("gemist: error parsing URL of media item")
+		url, ok = schemaValue(fallback, func(si *SchemaItem) string { return si.URL })
+	}
+	if !ok {
+		err = missingError("URL", pMIURL)
 		return
 	}
 
@@ -49,6 +56,11 @@ func parseMediaItem(n *xmlpath.Node) (mi MediaItem, err error) {
 			images = append(images, url)
 		}
 	}
+	if len(images) == 0 {
+		if img, ok := schemaValue(fallback, func(si *SchemaItem) string { return si.ThumbnailURL }); ok {
+			images = append(images, img)
+		}
+	}
 
 	mi.Title = title
 	mi.Description = desc
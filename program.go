@@ -1,9 +1,9 @@
 package gemist
 
 import (
-	"errors"
+	"context"
 	"io"
-	"net/http"
+	"iter"
 	"strconv"
 	"strings"
 	"time"
@@ -15,65 +15,124 @@ import (
 type Program struct {
 	MediaItem
 
-	bs []*BroadcastProxy
+	// Schema is the schema.org metadata of the program page, or nil if it
+	// has none.
+	Schema *Schema
+
+	bs  []*BroadcastProxy
+	nbs int
+	ss  []*Segment
+	nss int
 }
 
-// GetProgram gets the page content from url, parses it and returns a Program.
-func GetProgram(url string) (*Program, error) {
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// NumBroadcasts returns the total number of broadcasts of the program, which
+// can be more than are listed on the program page. Use Client.GetBroadcasts
+// to get all of them.
+func (p *Program) NumBroadcasts() int {
+	return p.nbs
+}
+
+// Broadcasts returns the broadcasts listed on the program page.
+func (p *Program) Broadcasts() []*BroadcastProxy {
+	bs := make([]*BroadcastProxy, len(p.bs))
+	copy(bs, p.bs)
+	return bs
+}
+
+// All returns an iterator over the broadcasts listed on the program page that
+// match all filters, in page order.
+func (p *Program) All(filters ...BroadcastFilter) iter.Seq[*BroadcastProxy] {
+	return func(yield func(*BroadcastProxy) bool) {
+		for _, bp := range p.bs {
+			if !matchAll(bp, filters) {
+				continue
+			}
+			if !yield(bp) {
+				return
+			}
+		}
 	}
+}
 
-	return ParseProgram(r.Body)
+// GetProgram gets the page content from url, parses it and returns a Program.
+// It uses DefaultClient.
+func GetProgram(url string) (*Program, error) {
+	return DefaultClient.GetProgram(context.Background(), url)
 }
 
-// ParseProgram parses content of a reader into a Program.
+// ParseProgram parses content of a reader into a Program. Relative broadcast
+// URLs are resolved against DefaultBaseURL, and broadcast dates without a year
+// are taken relative to the current time.
 func ParseProgram(r io.Reader) (*Program, error) {
+	return parseProgram(r, DefaultBaseURL, time.Now())
+}
+
+// parseProgram parses a program page. Broadcast dates without a year are taken
+// relative to now, the time the page was served.
+func parseProgram(r io.Reader, base string, now time.Time) (*Program, error) {
 	n, err := xmlpath.ParseHTML(r)
 	if err != nil {
 		return nil, err
 	}
 
-	mi, err := parseMediaItem(n)
+	schema := parseSchema(n, base)
+
+	mi, err := parseMediaItem(n, schema.items(schemaSeriesTypes...))
 	if err != nil {
 		return nil, err
 	}
 
 	// Get broadcast list node.
-	bs, err := parseProgramBroadcasts(n)
+	bs, nbs, err := parseProgramBroadcasts(n, base, now)
 	if err != nil {
 		return nil, err
 	}
 
 	p := Program{
 		MediaItem: mi,
+		Schema:    schema,
 		bs:        bs,
+		nbs:       nbs,
+	}
+
+	// Not all programs have segments.
+	if pPSData.Exists(n) {
+		p.ss, p.nss, err = parseProgramSegments(n, base)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &p, nil
 }
 
-var pPBData = xmlpath.MustCompile("//div[@id='broadcasts-block']/div[1]/div[1]")
+var pPBData = mustCompile("//div[@id='broadcasts-block']/div[1]/div[1]")
 
-func parseProgramBroadcasts(n *xmlpath.Node) ([]*BroadcastProxy, error) {
+// parseProgramBroadcasts parses the broadcast list of a program or archive
+// page. It returns the listed broadcasts and the total number of broadcasts.
+func parseProgramBroadcasts(n *xmlpath.Node, base string, now time.Time) ([]*BroadcastProxy, int, error) {
 	iter := pPBData.Iter(n)
-	iter.Next()
+	if !iter.Next() {
+		return nil, 0, missingError("broadcast list", pPBData)
+	}
 	l := iter.Node()
 
-	bs, err := newBroadcastProxySlice(l)
+	num, err := parseListLength(l, "broadcast list length")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	err = parseProgramList(l, &bs)
+	var bs []*BroadcastProxy
+	err = parseProgramList(l, base, now, &bs)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return bs, nil
+	return bs, num, nil
 }
 
+// BroadcastProxy represents a broadcast as listed on a program page. It only
+// carries the summary shown in the listing.
 type BroadcastProxy struct {
 	MediaItem
 	SubTitle string
@@ -81,28 +140,28 @@ type BroadcastProxy struct {
 	Length   time.Duration
 }
 
-var pPListNum = xmlpath.MustCompile("@data-num-found")
+var pPListNum = mustCompile("@data-num-found")
 
-func newBroadcastProxySlice(n *xmlpath.Node) ([]*BroadcastProxy, error) {
+// parseListLength parses the total number of items of a search results list.
+func parseListLength(n *xmlpath.Node, field string) (int, error) {
 	snum, ok := pPListNum.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list length")
+		return 0, missingError(field, pPListNum)
 	}
 
 	num, err := strconv.Atoi(snum)
 	if err != nil {
-		return nil, err
+		return 0, invalidError(field, pPListNum, snum, err)
 	}
 
-	bps := make([]*BroadcastProxy, 0, num)
-	return bps, nil
+	return num, nil
 }
 
 var (
-	pPListItems = xmlpath.MustCompile("div")
+	pPListItems = mustCompile("div")
 )
 
-func parseProgramList(n *xmlpath.Node, s *[]*BroadcastProxy) error {
+func parseProgramList(n *xmlpath.Node, base string, now time.Time, s *[]*BroadcastProxy) error {
 	iter := pPListItems.Iter(n)
 
 	var (
@@ -111,8 +170,9 @@ func parseProgramList(n *xmlpath.Node, s *[]*BroadcastProxy) error {
 	)
 
 	for iter.Next() {
-		bp, lerr := parseProgramListItem(iter.Node())
+		bp, lerr := parseProgramListItem(iter.Node(), base, now)
 		if lerr != nil {
+			err = lerr
 			break
 		}
 
@@ -131,43 +191,21 @@ func parseProgramList(n *xmlpath.Node, s *[]*BroadcastProxy) error {
 }
 
 var (
-	pPListItemTitle     = xmlpath.MustCompile("div[2]/a/h4/text()")
-	pPListItemDesc      = xmlpath.MustCompile("div[2]/a/p/text()")
-	pPListItemImage     = xmlpath.MustCompile("div[1]/div/a/img/@src")
-	pPListItemURL       = xmlpath.MustCompile("div[1]/div/a/@href")
-	pPListItemInfo      = xmlpath.MustCompile("div[2]/a/h5/text()")
-	pPListItemLen       = xmlpath.MustCompile("div[1]/div/a/div/text()")
+	pPListItemTitle     = mustCompile("div[2]/a/h4/text()")
+	pPListItemDesc      = mustCompile("div[2]/a/p/text()")
+	pPListItemImage     = mustCompile("div[1]/div/a/img/@src")
+	pPListItemURL       = mustCompile("div[1]/div/a/@href")
+	pPListItemInfo      = mustCompile("div[2]/a/h5/text()")
+	pPListItemLen       = mustCompile("div[1]/div/a/div/text()")
 	pListItemDateLoc, _ = time.LoadLocation("Europe/Amsterdam")
-	pListItemDateRep    = strings.NewReplacer(
-		"Ma", "Mon",
-		"Di", "Tue",
-		"Wo", "Wed",
-		"Do", "Thu",
-		"Vr", "Fri",
-		"Za", "Sat",
-		"Zo", "Sun",
-		"jan", "Jan",
-		"feb", "Feb",
-		"mrt", "Mar",
-		"apr", "Apr",
-		"mei", "May",
-		"jun", "Jun",
-		"jul", "Jul",
-		"aug", "Aug",
-		"sep", "Sep",
-		"okt", "Oct",
-		"nov", "Nov",
-		"dec", "Dec",
-	)
 )
 
-const urlBase = "http://www.npo.nl"
-const pListItemDateLayout = "Mon _2 Jan 2006 15:04"
-
-func parseProgramListItem(n *xmlpath.Node) (*BroadcastProxy, error) {
+// parseProgramListItem parses an item of a broadcast list. A date without a
+// year is taken relative to now, like ParseDutchDate does.
+func parseProgramListItem(n *xmlpath.Node, base string, now time.Time) (*BroadcastProxy, error) {
 	title, ok := pPListItemTitle.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list title")
+		return nil, missingError("list item title", pPListItemTitle)
 	}
 
 	// No need to exist, no description is valid.
@@ -175,35 +213,48 @@ func parseProgramListItem(n *xmlpath.Node) (*BroadcastProxy, error) {
 
 	img, ok := pPListItemImage.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list image")
+		return nil, missingError("list item image", pPListItemImage)
 	}
 
 	path, ok := pPListItemURL.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list url")
+		return nil, missingError("list item URL", pPListItemURL)
 	}
 
 	infostr, ok := pPListItemInfo.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list info")
+		return nil, missingError("list item info", pPListItemInfo)
 	}
 
-	info := strings.Split(infostr, " · ")
-
-	datestr := pListItemDateRep.Replace(info[1])
-	date, err := time.ParseInLocation(pListItemDateLayout, datestr, pListItemDateLoc)
+	// The info is the subtitle, the date and the length, but the subtitle
+	// can be missing. The subtitle can contain a date itself, so it is only
+	// taken as the date if no other part is one.
+	var (
+		info     = strings.Split(infostr, " · ")
+		subtitle = info[0]
+		date     time.Time
+		err      error
+	)
+	for _, part := range append(info[1:], info[0]) {
+		if date, err = ParseDutchDate(part, now); err == nil {
+			if part == info[0] {
+				subtitle = ""
+			}
+			break
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, invalidError("list item date", pPListItemInfo, infostr, err)
 	}
 
 	lenstr, ok := pPListItemLen.String(n)
 	if !ok {
-		return nil, errors.New("gemist: error parsing program list length")
+		return nil, missingError("list item length", pPListItemLen)
 	}
 
 	len, err := parseBroadcastLength(lenstr)
 	if err != nil {
-		return nil, err
+		return nil, invalidError("list item length", pPListItemLen, lenstr, err)
 	}
 
 	bp := BroadcastProxy{
@@ -211,9 +262,9 @@ func parseProgramListItem(n *xmlpath.Node) (*BroadcastProxy, error) {
 			Title:       strings.TrimSpace(title),
 			Description: desc,
 			ImageURLs:   []string{img},
-			URL:         urlBase + path,
+			URL:         base + path,
 		},
-		SubTitle: info[0],
+		SubTitle: subtitle,
 		Date:     date,
 		Length:   len,
 	}
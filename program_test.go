@@ -1,12 +1,14 @@
 package gemist
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/xmlpath.v2"
 )
 
 func TestParseProgram(t *testing.T) {
@@ -143,6 +145,21 @@ func TestParseProgram(t *testing.T) {
 	assert.Equal(_p.ImageURLs, p.ImageURLs, "image URLs not equal")
 	assert.Equal(_p.URL, p.URL, "program URL not equal")
 	assert.Len(p.bs, len(_p.bs), "broadcast proxy list length not correct")
+	assert.Equal(693, p.NumBroadcasts(), "number of broadcasts not equal")
+	assert.Len(p.Segments(), 8, "segment list length not correct")
+	assert.Equal(139, p.NumSegments(), "number of segments not equal")
+	if ss := p.Segments(); len(ss) > 0 {
+		assert.Equal(&Segment{
+			MediaItem: MediaItem{
+				Title:     "Radio Bergeijk 00:45 uur 28 Dec 2001",
+				ImageURLs: []string{"http://images.poms.omroep.nl/image/s265/c265x150/215303.png"},
+				URL:       "http://www.npo.nl/radio-bergeijk/28-12-2001/POMS_VPRO_396637/POMS_VPRO_396638",
+			},
+			Length:    time.Hour,
+			ParentID:  "POMS_VPRO_396637",
+			ParentURL: "http://www.npo.nl/radio-bergeijk/28-12-2001/POMS_VPRO_396637",
+		}, ss[0], "first segment not equal")
+	}
 	for i := 0; i < len(_p.bs); i++ {
 		_bp, bp := _p.bs[i], p.bs[i]
 
@@ -156,6 +173,45 @@ func TestParseProgram(t *testing.T) {
 	}
 }
 
+func TestProgram_All(t *testing.T) {
+	assert := assert.New(t)
+
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	p := Program{
+		bs: []*BroadcastProxy{
+			{MediaItem: MediaItem{Title: "Radio Bergeijk : De allerlaatste !"}, Date: time.Date(2007, time.October, 6, 18, 32, 0, 0, l)},
+			{MediaItem: MediaItem{Title: "Radio Bergeijk"}, Date: time.Date(2007, time.September, 22, 18, 32, 0, 0, l)},
+			{MediaItem: MediaItem{Title: "Geen Radio Bergeijk"}, Date: time.Date(2007, time.August, 25, 18, 32, 0, 0, l)},
+		},
+	}
+
+	bs := p.Broadcasts()
+	assert.Equal(p.bs, bs, "broadcast list not equal")
+	bs[0] = nil
+	assert.NotNil(p.bs[0], "broadcast list not copied")
+
+	var titles []string
+	for bp := range p.All(TitleContains("radio bergeijk")) {
+		titles = append(titles, bp.Title)
+	}
+	assert.Len(titles, 3, "title filter not applied")
+
+	titles = nil
+	for bp := range p.All(TitleMatches(regexp.MustCompile(`^Radio`)), Between(time.Date(2007, time.September, 1, 0, 0, 0, 0, l), time.Time{})) {
+		titles = append(titles, bp.Title)
+	}
+	assert.Equal([]string{"Radio Bergeijk : De allerlaatste !", "Radio Bergeijk"}, titles, "filters not applied")
+
+	titles = nil
+	for bp := range p.All(Between(time.Time{}, time.Date(2007, time.October, 6, 18, 32, 0, 0, l))) {
+		titles = append(titles, bp.Title)
+		break
+	}
+	assert.Equal([]string{"Radio Bergeijk"}, titles, "iteration did not stop")
+}
+
 var testDataProgramBroadcast = `<!DOCTYPE html>
 <html>
 <head>
@@ -923,3 +979,56 @@ window._fbq.push(['track', 'PixelInitialized', {}]);
 </body>
 </html>
 `
+
+func TestParseProgram_invalidItem(t *testing.T) {
+	page := testProgramPage(3, 2, 1)
+	require.Contains(t, page, "Za 2 okt 2007 18:32")
+	page = strings.Replace(page, "Za 2 okt 2007 18:32", "Elke zaterdag om half 7", 1)
+
+	_, err := ParseProgram(strings.NewReader(page))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	var perr *ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, "list item date", perr.Field)
+	}
+}
+
+func TestParseProgramListItem(t *testing.T) {
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	item := func(info string) *xmlpath.Node {
+		n, err := xmlpath.ParseHTML(strings.NewReader(`<html><body><div class='list-item'>
+<div class='span4'><div class='image-container'><a href="/radio-bergeijk/06-10-2007/POMS_VPRO_396279"><img src="http://images.poms.omroep.nl/image/s174/c174x98/215303.png" />
+<div class="overlay-icon">25:02</div></a></div></div>
+<div class='span8'><a href="/radio-bergeijk/06-10-2007/POMS_VPRO_396279"><h4>Radio Bergeijk</h4><h5>` + info + `</h5></a></div>
+</div></body></html>`))
+		require.NoError(t, err)
+		iter := mustCompile("//div[@class='list-item']").Iter(n)
+		require.True(t, iter.Next())
+		return iter.Node()
+	}
+
+	now := time.Date(2008, time.March, 1, 12, 0, 0, 0, l)
+
+	bp, err := parseProgramListItem(item("Za 6 okt 2007 18:32 · 25 min"), DefaultBaseURL, now)
+	if assert.NoError(t, err) {
+		assert.Empty(t, bp.SubTitle, "sub title not empty")
+		assert.True(t, time.Date(2007, time.October, 6, 18, 32, 0, 0, l).Equal(bp.Date), "date not equal (%v)", bp.Date)
+	}
+
+	bp, err = parseProgramListItem(item("Zaterdag 6 oktober 2007 om half 7 's avonds"), DefaultBaseURL, now)
+	if assert.NoError(t, err) {
+		assert.True(t, time.Date(2007, time.October, 6, 18, 30, 0, 0, l).Equal(bp.Date), "date not equal (%v)", bp.Date)
+	}
+
+	// A date without a year is in the year before the page was served.
+	bp, err = parseProgramListItem(item("Za 6 okt 18:32 · 25 min"), DefaultBaseURL, now)
+	if assert.NoError(t, err) {
+		assert.True(t, time.Date(2007, time.October, 6, 18, 32, 0, 0, l).Equal(bp.Date), "date not equal (%v)", bp.Date)
+	}
+
+	_, err = parseProgramListItem(item("Elke zaterdagavond om Half 7 op Radio 1"), DefaultBaseURL, now)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
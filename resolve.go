@@ -0,0 +1,203 @@
+package gemist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A MismatchError is returned when a resolved broadcast does not agree with
+// the summary of its BroadcastProxy. The resolved broadcast is still returned
+// alongside the error.
+type MismatchError struct {
+	Proxy     *BroadcastProxy
+	Broadcast *Broadcast
+	Fields    []string // names of the mismatching fields
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("gemist: broadcast %s does not match program listing (%s)",
+		e.Proxy.URL, strings.Join(e.Fields, ", "))
+}
+
+// Resolve gets the broadcast page the proxy refers to and returns the parsed
+// Broadcast. It uses DefaultClient.
+func (bp *BroadcastProxy) Resolve(ctx context.Context) (*Broadcast, error) {
+	return DefaultClient.Resolve(ctx, bp)
+}
+
+// ResolveAll resolves all broadcasts listed on the program page. It uses
+// DefaultClient.
+func (p *Program) ResolveAll(ctx context.Context) ([]*Broadcast, error) {
+	return DefaultClient.ResolveAll(ctx, p)
+}
+
+// FetchProgram gets the page of the program the broadcast belongs to and
+// returns the parsed Program. It uses DefaultClient.
+func (b *Broadcast) FetchProgram(ctx context.Context) (*Program, error) {
+	return DefaultClient.FetchProgram(ctx, b)
+}
+
+// FetchProgram gets the page of the program b belongs to, as referred to by
+// b.Program, and returns the parsed Program. If b has no program reference,
+// ErrNoProgram is returned.
+func (c *Client) FetchProgram(ctx context.Context, b *Broadcast) (*Program, error) {
+	ref := b.Program
+	if ref == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoProgram, b.URL)
+	}
+
+	url := ref.URL
+	if url == "" {
+		url = string(ref.ID)
+	}
+	return c.GetProgram(ctx, url)
+}
+
+// Resolve gets the broadcast page bp refers to and returns the parsed
+// Broadcast. If the date or length of the broadcast differs from bp, both the
+// Broadcast and a *MismatchError are returned.
+func (c *Client) Resolve(ctx context.Context, bp *BroadcastProxy) (*Broadcast, error) {
+	b, err := c.GetBroadcast(ctx, bp.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, checkBroadcast(bp, b)
+}
+
+// ResolveAll resolves all broadcasts listed on the program page, in page
+// order. Resolving stops at the first error getting or parsing a page, with
+// the broadcasts resolved so far returned. Mismatches do not stop resolving;
+// they are joined and returned after all broadcasts are resolved.
+func (c *Client) ResolveAll(ctx context.Context, p *Program) ([]*Broadcast, error) {
+	var (
+		bs   = make([]*Broadcast, 0, len(p.bs))
+		errs []error
+	)
+
+	for _, bp := range p.bs {
+		b, err := c.Resolve(ctx, bp)
+		if b == nil {
+			return bs, err
+		}
+
+		bs = append(bs, b)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return bs, errors.Join(errs...)
+}
+
+// DefaultResolveWorkers is the number of broadcasts ResolveMany resolves at
+// once, if ResolveOptions.Workers is zero.
+const DefaultResolveWorkers = 4
+
+// ResolveOptions configures ResolveMany.
+type ResolveOptions struct {
+	// Workers is the number of broadcasts resolved at once. If zero,
+	// DefaultResolveWorkers is used.
+	Workers int
+
+	// OnResult, if set, is called with every result as it completes, in
+	// completion order. It is called from the goroutine that called
+	// ResolveMany, one result at a time.
+	OnResult func(ResolveResult)
+}
+
+// A ResolveResult is the result of resolving one BroadcastProxy.
+type ResolveResult struct {
+	Index     int // index of Proxy in the input
+	Proxy     *BroadcastProxy
+	Broadcast *Broadcast // also set if Err is a *MismatchError
+	Err       error
+}
+
+// ResolveMany resolves the proxies in parallel. It uses DefaultClient.
+func ResolveMany(ctx context.Context, bps []*BroadcastProxy, opts ResolveOptions) []ResolveResult {
+	return DefaultClient.ResolveMany(ctx, bps, opts)
+}
+
+// ResolveMany resolves the proxies in parallel and returns the results in
+// input order. An error resolving one proxy is recorded in its result and
+// does not stop the others. If ctx is done, the proxies not yet resolved get
+// the error of ctx.
+func (c *Client) ResolveMany(ctx context.Context, bps []*BroadcastProxy, opts ResolveOptions) []ResolveResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultResolveWorkers
+	}
+	if workers > len(bps) {
+		workers = len(bps)
+	}
+
+	var (
+		jobs    = make(chan int)
+		results = make(chan ResolveResult)
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := ResolveResult{Index: i, Proxy: bps[i]}
+				if r.Err = ctx.Err(); r.Err == nil {
+					r.Broadcast, r.Err = c.Resolve(ctx, bps[i])
+				}
+				results <- r
+			}
+		}()
+	}
+
+	go func() {
+		for i := range bps {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	rs := make([]ResolveResult, len(bps))
+	for r := range results {
+		rs[r.Index] = r
+		if opts.OnResult != nil {
+			opts.OnResult(r)
+		}
+	}
+
+	return rs
+}
+
+// lengthTolerance is the difference in length allowed between a proxy and its
+// broadcast, since listings and pages round lengths differently.
+const lengthTolerance = time.Second
+
+func checkBroadcast(bp *BroadcastProxy, b *Broadcast) error {
+	var fields []string
+
+	// Program listings show the date with minute precision.
+	if !bp.Date.IsZero() && !bp.Date.Equal(b.Date.Truncate(time.Minute)) {
+		fields = append(fields, "date")
+	}
+
+	if bp.Length != 0 {
+		d := bp.Length - b.Length
+		if d < -lengthTolerance || d > lengthTolerance {
+			fields = append(fields, "length")
+		}
+	}
+
+	if fields == nil {
+		return nil
+	}
+
+	return &MismatchError{Proxy: bp, Broadcast: b, Fields: fields}
+}
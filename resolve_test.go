@@ -0,0 +1,196 @@
+package gemist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastProxy_Resolve(t *testing.T) {
+	assert := assert.New(t)
+
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testDataBroadcastAudio))
+	}))
+	defer s.Close()
+
+	bp := BroadcastProxy{
+		MediaItem: MediaItem{URL: s.URL + "/radio-bergeijk/03-04-2001/POMS_VPRO_396139"},
+		Date:      time.Date(2001, time.April, 3, 0, 44, 0, 0, l),
+		Length:    885 * time.Second,
+	}
+
+	b, err := bp.Resolve(context.Background())
+	if assert.NoError(err) {
+		assert.Equal("Radio bergeijk - Radio Bergeijk", b.Title, "title not equal")
+	}
+
+	bp.Date = bp.Date.Add(24 * time.Hour)
+	bp.Length = 15 * time.Minute
+
+	b, err = bp.Resolve(context.Background())
+	assert.NotNil(b, "broadcast not returned on mismatch")
+
+	var merr *MismatchError
+	if assert.True(errors.As(err, &merr), "error is not a mismatch error") {
+		assert.Equal([]string{"date", "length"}, merr.Fields, "mismatching fields not equal")
+	}
+}
+
+func TestCheckBroadcast(t *testing.T) {
+	assert := assert.New(t)
+
+	d := time.Date(2007, time.October, 6, 18, 32, 0, 0, time.UTC)
+	bp := &BroadcastProxy{Date: d, Length: 1502 * time.Second}
+
+	assert.NoError(checkBroadcast(bp, &Broadcast{Date: d.Add(40 * time.Second), Length: 1502 * time.Second}))
+	assert.NoError(checkBroadcast(&BroadcastProxy{}, &Broadcast{Date: d, Length: time.Hour}))
+	assert.Error(checkBroadcast(bp, &Broadcast{Date: d.Add(time.Minute), Length: 1502 * time.Second}))
+	assert.Error(checkBroadcast(bp, &Broadcast{Date: d, Length: 1500 * time.Second}))
+}
+
+func TestClient_ResolveMany(t *testing.T) {
+	// The requests wait until three of them have arrived, which shows that
+	// the workers run at the same time.
+	var (
+		cur, max, arrived int32
+		full              = make(chan struct{})
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		defer atomic.AddInt32(&cur, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		if atomic.AddInt32(&arrived, 1) == 3 {
+			close(full)
+		}
+		select {
+		case <-full:
+		case <-time.After(5 * time.Second):
+		}
+
+		if strings.HasSuffix(r.URL.Path, "_3") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testDataBroadcastAudio))
+	}))
+	defer s.Close()
+
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	var bps []*BroadcastProxy
+	for i := 0; i < 10; i++ {
+		bps = append(bps, &BroadcastProxy{
+			MediaItem: MediaItem{URL: fmt.Sprintf("%s/radio-bergeijk/03-04-2001/POMS_VPRO_%d", s.URL, i)},
+			Date:      time.Date(2001, time.April, 3, 0, 44, 0, 0, l),
+			Length:    885 * time.Second,
+		})
+	}
+	bps[5].Length = time.Minute
+
+	var streamed []int
+	rs := (&Client{}).ResolveMany(context.Background(), bps, ResolveOptions{
+		Workers:  3,
+		OnResult: func(r ResolveResult) { streamed = append(streamed, r.Index) },
+	})
+
+	require.Len(t, rs, 10)
+	assert.Len(t, streamed, 10)
+	assert.True(t, atomic.LoadInt32(&max) <= 3, "%d concurrent requests", atomic.LoadInt32(&max))
+	select {
+	case <-full:
+	default:
+		t.Error("workers did not run concurrently")
+	}
+
+	for i, r := range rs {
+		assert.Equal(t, i, r.Index)
+		assert.Equal(t, bps[i], r.Proxy)
+
+		switch i {
+		case 3:
+			assert.ErrorIs(t, r.Err, ErrNotFound)
+			assert.Nil(t, r.Broadcast)
+		case 5:
+			var merr *MismatchError
+			assert.ErrorAs(t, r.Err, &merr)
+			assert.NotNil(t, r.Broadcast)
+		default:
+			assert.NoError(t, r.Err)
+			if assert.NotNil(t, r.Broadcast) {
+				assert.Equal(t, "Radio bergeijk - Radio Bergeijk", r.Broadcast.Title)
+			}
+		}
+	}
+}
+
+func TestClient_ResolveMany_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bps := []*BroadcastProxy{
+		{MediaItem: MediaItem{URL: "http://www.npo.nl/x/POMS_VPRO_1"}},
+		{MediaItem: MediaItem{URL: "http://www.npo.nl/x/POMS_VPRO_2"}},
+	}
+
+	rs := (&Client{}).ResolveMany(ctx, bps, ResolveOptions{})
+	require.Len(t, rs, 2)
+	for _, r := range rs {
+		assert.ErrorIs(t, r.Err, context.Canceled)
+	}
+
+	assert.Empty(t, (&Client{}).ResolveMany(ctx, nil, ResolveOptions{}))
+}
+
+func TestBroadcast_FetchProgram(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/POMS_S_VPRO_396280":
+			http.Redirect(w, r, "/radio-bergeijk/POMS_S_VPRO_396280", http.StatusFound)
+		case "/radio-bergeijk/POMS_S_VPRO_396280":
+			w.Write([]byte(testDataProgramBroadcast))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	c := Client{BaseURL: s.URL}
+
+	b, err := ParseBroadcast(strings.NewReader(testDataBroadcastAudio))
+	require.NoError(t, err)
+
+	p, err := c.FetchProgram(context.Background(), b)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Radio Bergeijk", p.Title)
+		assert.Equal(t, MediaID("POMS_S_VPRO_396280"), p.ID())
+	}
+
+	b.Program.URL = ""
+	p, err = c.FetchProgram(context.Background(), b)
+	if assert.NoError(t, err, "program not requested by ID") {
+		assert.Equal(t, "Radio Bergeijk", p.Title)
+	}
+
+	b.Program = nil
+	_, err = c.FetchProgram(context.Background(), b)
+	assert.ErrorIs(t, err, ErrNoProgram)
+}
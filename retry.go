@@ -0,0 +1,318 @@
+package gemist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A RetryPolicy controls how a Client retries failed requests. Requests are
+// retried on 429 and 5xx gateway and availability responses, and on
+// transient network errors. The delay before a retry grows exponentially
+// with jitter, but is at least the delay asked for in a Retry-After header.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request,
+	// including the first. If zero, 3 is used.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry, and MaxBackoff the
+	// maximum delay before a retry. If zero, 500ms and 30s are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a RetryPolicy for polite crawling.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+// An Attempt describes an attempt of a request, as reported to the OnAttempt
+// hook of a Client.
+type Attempt struct {
+	Request  *http.Request
+	Number   int            // 1 for the first attempt
+	Response *http.Response // nil if Err is set
+	Err      error
+	Retry    bool          // whether the request is retried
+	Wait     time.Duration // delay before the retry
+}
+
+// retryStatus are the status codes of responses that are retried.
+var retryStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retry reports whether attempt n, which ended with r or err, is retried,
+// and the delay before the retry.
+func (p *RetryPolicy) retry(n int, r *http.Response, err error) (time.Duration, bool) {
+	if p == nil {
+		return 0, false
+	}
+
+	max := p.MaxAttempts
+	if max <= 0 {
+		max = 3
+	}
+	if n >= max {
+		return 0, false
+	}
+
+	if err != nil && !isTransient(err) || err == nil && !retryStatus[r.StatusCode] {
+		return 0, false
+	}
+
+	min, maxb := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = 500 * time.Millisecond
+	}
+	if maxb <= 0 {
+		maxb = 30 * time.Second
+	}
+
+	d := min
+	for i := 1; i < n && d < maxb; i++ {
+		d *= 2
+	}
+	if d > maxb {
+		d = maxb
+	}
+
+	// Wait between half and all of the backoff, to spread retries.
+	d = d/2 + rand.N(d/2+1)
+
+	if r != nil {
+		if ra := parseRetryAfter(r.Header.Get("Retry-After"), time.Now()); ra > d {
+			d = ra
+		}
+	}
+
+	return d, true
+}
+
+// isTransient reports whether err is a network error that may not occur
+// when the request is retried: a timeout, or a connection that is refused,
+// reset or closed early. Errors that are permanent, like an unknown host, an
+// unsupported scheme or an invalid certificate, are not transient.
+func isTransient(err error) bool {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = uerr.Err
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout() ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// send sends req, retrying according to the retry policy of the client. Unlike
+// get, it does not check the status code.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for n := 1; ; n++ {
+		r, err := c.sendOnce(req)
+		wait, retry := c.Retry.retry(n, r, err)
+
+		if c.OnAttempt != nil {
+			c.OnAttempt(Attempt{
+				Request:  req,
+				Number:   n,
+				Response: r,
+				Err:      err,
+				Retry:    retry,
+				Wait:     wait,
+			})
+		}
+
+		if !retry {
+			return r, err
+		}
+		if r != nil {
+			discard(r)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// sendOnce sends req once, within the rate limit of its host.
+func (c *Client) sendOnce(req *http.Request) (*http.Response, error) {
+	l := c.limiter(req.URL.Host)
+	if l == nil {
+		return c.httpClient().Do(req)
+	}
+
+	if err := l.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	r, err := c.httpClient().Do(req)
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+
+	// The request is in flight until its body is closed.
+	r.Body = &releaseBody{ReadCloser: r.Body, release: l.release}
+	return r, nil
+}
+
+// A RateLimit limits the requests a Client makes to each host.
+type RateLimit struct {
+	// Rate is the sustained number of requests per second. If zero, the
+	// rate is not limited.
+	Rate float64
+
+	// Burst is the number of requests that can be made at once, above
+	// the rate. If less than 1, 1 is used.
+	Burst int
+
+	// MaxConcurrent is the maximum number of requests in flight. A
+	// request is in flight until the body of its response is closed. If
+	// zero, the number is not limited.
+	MaxConcurrent int
+}
+
+// hostLimiter limits the requests to a host with a token bucket and a
+// semaphore.
+type hostLimiter struct {
+	rate  float64
+	burst float64
+	sem   chan struct{} // nil if concurrency is not limited
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (c *Client) limiter(host string) *hostLimiter {
+	rl := c.RateLimit
+	if rl == nil || rl.Rate <= 0 && rl.MaxConcurrent <= 0 {
+		return nil
+	}
+
+	c.limitMu.Lock()
+	defer c.limitMu.Unlock()
+
+	if l, ok := c.limiters[host]; ok {
+		return l
+	}
+
+	burst := float64(rl.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &hostLimiter{rate: rl.Rate, burst: burst, tokens: burst}
+	if rl.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, rl.MaxConcurrent)
+	}
+
+	if c.limiters == nil {
+		c.limiters = make(map[string]*hostLimiter)
+	}
+	c.limiters[host] = l
+	return l
+}
+
+// acquire waits until a request can be made.
+func (l *hostLimiter) acquire(ctx context.Context) error {
+	if err := l.take(ctx); err != nil {
+		return err
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// take waits for a token of the bucket.
+func (l *hostLimiter) take(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if l.tokens > l.burst {
+				l.tokens = l.burst
+			}
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (l *hostLimiter) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// releaseBody is a response body that releases its request when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,271 @@
+package gemist
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_retry(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer s.Close()
+
+	var attempts []Attempt
+	c := &Client{
+		Retry:     &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond},
+		OnAttempt: func(a Attempt) { attempts = append(attempts, a) },
+	}
+
+	body, _, err := c.getBytes(context.Background(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	require.Len(t, attempts, 3)
+	for i, a := range attempts {
+		assert.Equal(t, i+1, a.Number)
+		assert.NoError(t, a.Err)
+		assert.Equal(t, i < 2, a.Retry)
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, attempts[0].Response.StatusCode)
+	assert.True(t, attempts[1].Wait <= 2*time.Millisecond)
+	assert.Equal(t, http.StatusOK, attempts[2].Response.StatusCode)
+
+	// Attempts are limited.
+	atomic.StoreInt32(&n, -10)
+	attempts = nil
+	_, _, err = c.getBytes(context.Background(), s.URL)
+	var herr *HTTPError
+	require.ErrorAs(t, err, &herr)
+	assert.Equal(t, http.StatusServiceUnavailable, herr.StatusCode)
+	assert.Len(t, attempts, 3)
+}
+
+func TestClient_retry_notRetried(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		http.NotFound(w, r)
+	}))
+	defer s.Close()
+
+	c := &Client{Retry: &RetryPolicy{MinBackoff: time.Millisecond}}
+	_, err := c.GetBroadcast(context.Background(), s.URL+"/x/POMS_VPRO_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+
+	// Without a policy, nothing is retried.
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	_, err = (&Client{}).GetBroadcast(context.Background(), s.URL+"/x/POMS_VPRO_1")
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&n))
+}
+
+func TestClient_retry_retryAfter(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wait time.Duration
+	c := &Client{
+		Retry: &RetryPolicy{MinBackoff: time.Millisecond},
+		OnAttempt: func(a Attempt) {
+			wait = a.Wait
+			cancel()
+		},
+	}
+
+	_, _, err := c.getBytes(ctx, s.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2*time.Minute, wait)
+}
+
+func TestClient_retry_network(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := s.URL
+	s.Close()
+
+	var attempts []Attempt
+	c := &Client{
+		Retry:     &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond},
+		OnAttempt: func(a Attempt) { attempts = append(attempts, a) },
+	}
+
+	_, _, err := c.getBytes(context.Background(), url)
+	assert.Error(t, err)
+	require.Len(t, attempts, 2)
+	assert.Error(t, attempts[0].Err)
+	assert.True(t, attempts[0].Retry)
+	assert.Nil(t, attempts[0].Response)
+}
+
+func TestClient_retry_dropped(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			// Close the connection without a response.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer s.Close()
+
+	var attempts []Attempt
+	c := &Client{
+		Retry:     &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond},
+		OnAttempt: func(a Attempt) { attempts = append(attempts, a) },
+	}
+
+	body, _, err := c.getBytes(context.Background(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+	require.Len(t, attempts, 2)
+	assert.ErrorIs(t, attempts[0].Err, io.EOF)
+	assert.True(t, attempts[0].Retry)
+}
+
+func TestClient_retry_permanent(t *testing.T) {
+	notFound := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: "www.npo.invalid", IsNotFound: true}
+		},
+	}
+
+	for _, tt := range []struct {
+		url string
+		c   *http.Client
+	}{
+		{"ftp://www.npo.nl/radio-bergeijk", nil},
+		{"http://www.npo.invalid/radio-bergeijk", &http.Client{Transport: notFound}},
+	} {
+		var attempts []Attempt
+		c := &Client{
+			HTTPClient: tt.c,
+			Retry:      &RetryPolicy{MaxAttempts: 4, MinBackoff: time.Millisecond},
+			OnAttempt:  func(a Attempt) { attempts = append(attempts, a) },
+		}
+
+		_, _, err := c.getBytes(context.Background(), tt.url)
+		assert.Error(t, err, tt.url)
+		if assert.Len(t, attempts, 1, tt.url) {
+			assert.False(t, attempts[0].Retry, tt.url)
+		}
+	}
+}
+
+func TestRetryPolicy_retry(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 10, MinBackoff: time.Second, MaxBackoff: 4 * time.Second}
+	r := &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{}}
+
+	for n, max := range []time.Duration{1, 2, 4, 4, 4} {
+		d, ok := p.retry(n+1, r, nil)
+		assert.True(t, ok)
+		assert.True(t, d >= max*time.Second/2 && d <= max*time.Second, "attempt %d: %v", n+1, d)
+	}
+
+	_, ok := p.retry(10, r, nil)
+	assert.False(t, ok)
+	_, ok = p.retry(1, &http.Response{StatusCode: http.StatusForbidden}, nil)
+	assert.False(t, ok)
+	_, ok = p.retry(1, nil, context.Canceled)
+	assert.False(t, ok)
+	_, ok = p.retry(1, nil, io.ErrUnexpectedEOF)
+	assert.True(t, ok)
+	_, ok = p.retry(1, nil, &url.Error{Op: "Get", URL: "http://www.npo.nl", Err: io.EOF})
+	assert.True(t, ok)
+	_, ok = p.retry(1, nil, errors.New("unsupported protocol scheme"))
+	assert.False(t, ok)
+	_, ok = p.retry(1, nil, &url.Error{Op: "Get", URL: "http://www.npo.nl", Err: syscall.ECONNRESET})
+	assert.True(t, ok)
+	_, ok = p.retry(1, nil, &url.Error{Op: "Get", URL: "http://www.npo.nl", Err: x509.UnknownAuthorityError{}})
+	assert.False(t, ok)
+	_, ok = (*RetryPolicy)(nil).retry(1, r, nil)
+	assert.False(t, ok)
+}
+
+func TestClient_rateLimit(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	c := &Client{RateLimit: &RateLimit{Rate: 50, Burst: 2}}
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, _, err := c.getBytes(context.Background(), s.URL)
+		require.NoError(t, err)
+	}
+
+	// Two requests are made at once, the other four at 20ms intervals.
+	assert.True(t, time.Since(start) >= 70*time.Millisecond, "took %v", time.Since(start))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	c.RateLimit = &RateLimit{Rate: 0.001}
+	c.limiters = nil
+	_, _, err := c.getBytes(context.Background(), s.URL)
+	require.NoError(t, err)
+	_, _, err = c.getBytes(ctx, s.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_rateLimit_concurrent(t *testing.T) {
+	var cur, max int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+	}))
+	defer s.Close()
+
+	c := &Client{RateLimit: &RateLimit{MaxConcurrent: 2}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.getBytes(context.Background(), s.URL)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+}
@@ -0,0 +1,290 @@
+package gemist
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/xmlpath.v2"
+)
+
+// Schema is the schema.org metadata embedded in a page as microdata or
+// JSON-LD, and its breadcrumb trail. Parsing falls back to it when the Open
+// Graph tags or the page layout don't provide a field.
+type Schema struct {
+	Items       []*SchemaItem // in page order, microdata before JSON-LD
+	Breadcrumbs []Breadcrumb
+}
+
+// A SchemaItem is a schema.org item, like a TVSeries, TVEpisode or
+// VideoObject. Properties that are not on the page are left empty.
+type SchemaItem struct {
+	Type         string // like "http://schema.org/TVEpisode" or "TVEpisode"
+	Name         string
+	Description  string
+	URL          string
+	ThumbnailURL string
+	ContentURL   string
+	Duration     time.Duration
+	StartDate    time.Time
+}
+
+// A Breadcrumb is an entry of the breadcrumb trail of a page, from the home
+// page to the page itself.
+type Breadcrumb struct {
+	Title string
+	URL   string
+}
+
+// Item returns the first item with one of types, or nil if there is none.
+// Types are matched without their vocabulary, so "TVEpisode" matches both
+// "http://schema.org/TVEpisode" and "TVEpisode". Item can be called on a nil
+// schema.
+func (s *Schema) Item(types ...string) *SchemaItem {
+	if s == nil {
+		return nil
+	}
+	for _, si := range s.Items {
+		for _, t := range types {
+			if schemaTypeName(si.Type) == t {
+				return si
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypeName returns the name of type t without its vocabulary.
+func schemaTypeName(t string) string {
+	return t[strings.LastIndexAny(t, "/#:")+1:]
+}
+
+// items returns the items with one of types, ordered by type and then by
+// page order.
+func (s *Schema) items(types ...string) []*SchemaItem {
+	if s == nil {
+		return nil
+	}
+	var sis []*SchemaItem
+	for _, t := range types {
+		for _, si := range s.Items {
+			if schemaTypeName(si.Type) == t {
+				sis = append(sis, si)
+			}
+		}
+	}
+	return sis
+}
+
+// schemaValue returns the first non-empty value of field of sis.
+func schemaValue[T comparable](sis []*SchemaItem, field func(*SchemaItem) T) (T, bool) {
+	var zero T
+	for _, si := range sis {
+		if v := field(si); v != zero {
+			return v, true
+		}
+	}
+	return zero, false
+}
+
+// Schema types of the items of pages, in order of preference.
+var (
+	schemaEpisodeTypes = []string{"TVEpisode", "RadioEpisode", "Episode", "VideoObject", "AudioObject", "MediaObject"}
+	schemaMediaTypes   = []string{"VideoObject", "AudioObject", "MediaObject", "TVEpisode", "RadioEpisode", "Episode"}
+	schemaEventTypes   = []string{"BroadcastEvent", "PublicationEvent", "TVEpisode", "RadioEpisode", "Episode"}
+	schemaSeriesTypes  = []string{"TVSeries", "RadioSeries", "CreativeWorkSeries"}
+)
+
+var (
+	pSItems       = mustCompile("//*[@itemtype]")
+	pSItemScope   = mustCompile("@itemscope")
+	pSItemType    = mustCompile("@itemtype")
+	pSBreadcrumbs = mustCompile("//*[@itemtype='http://data-vocabulary.org/Breadcrumb']")
+	pSJSONLD      = mustCompile("//script[@type='application/ld+json']")
+)
+
+const breadcrumbType = "http://data-vocabulary.org/Breadcrumb"
+
+// schemaProps are the selectors of the microdata properties by name, in order
+// of preference. Properties of an item are its children and grandchildren,
+// which keeps the properties of nested items out on NPO pages.
+var schemaProps = func() map[string][]*selector {
+	m := make(map[string][]*selector)
+	for _, name := range []string{"name", "description", "url", "thumbnailUrl", "contentUrl", "duration", "startDate", "title"} {
+		for _, level := range []string{"", "*/"} {
+			p := level + "*[@itemprop='" + name + "']"
+			m[name] = append(m[name],
+				mustCompile(p+"/@content"),
+				mustCompile(p+"/@href"),
+				mustCompile(p+"/@src"),
+				mustCompile(p),
+			)
+		}
+	}
+	return m
+}()
+
+// parseSchema parses the schema.org microdata, JSON-LD and data-vocabulary.org
+// breadcrumbs of a page. Relative URLs are resolved against base. It returns
+// nil if the page has none. Invalid values are ignored.
+func parseSchema(n *xmlpath.Node, base string) *Schema {
+	var s Schema
+
+	iter := pSItems.Iter(n)
+	for iter.Next() {
+		in := iter.Node()
+		typ, _ := pSItemType.String(in)
+		if typ == breadcrumbType || !pSItemScope.Exists(in) {
+			continue
+		}
+
+		si := SchemaItem{
+			Type:         typ,
+			Name:         schemaProp(in, "name"),
+			Description:  schemaProp(in, "description"),
+			URL:          absURL(base, schemaProp(in, "url")),
+			ThumbnailURL: absURL(base, schemaProp(in, "thumbnailUrl")),
+			ContentURL:   absURL(base, schemaProp(in, "contentUrl")),
+		}
+		si.Duration, _ = parseISODuration(schemaProp(in, "duration"))
+		si.StartDate = parseSchemaDate(schemaProp(in, "startDate"))
+		s.Items = append(s.Items, &si)
+	}
+
+	iter = pSBreadcrumbs.Iter(n)
+	for iter.Next() {
+		bn := iter.Node()
+		s.Breadcrumbs = append(s.Breadcrumbs, Breadcrumb{
+			Title: schemaProp(bn, "title"),
+			URL:   absURL(base, schemaProp(bn, "url")),
+		})
+	}
+
+	iter = pSJSONLD.Iter(n)
+	for iter.Next() {
+		var v any
+		if json.Unmarshal(iter.Node().Bytes(), &v) == nil {
+			s.addJSONLD(v, base)
+		}
+	}
+
+	if len(s.Items) == 0 && len(s.Breadcrumbs) == 0 {
+		return nil
+	}
+	return &s
+}
+
+// schemaProp returns the value of microdata property name of item n.
+func schemaProp(n *xmlpath.Node, name string) string {
+	for _, p := range schemaProps[name] {
+		if v, ok := p.String(n); ok {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// addJSONLD adds the items and breadcrumbs of JSON-LD value v, including the
+// ones nested in it.
+func (s *Schema) addJSONLD(v any, base string) {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			s.addJSONLD(e, base)
+		}
+
+	case map[string]any:
+		typ := ldString(v["@type"])
+		switch {
+		case schemaTypeName(typ) == "BreadcrumbList":
+			s.addJSONLDBreadcrumbs(v, base)
+			return
+		case typ != "":
+			si := SchemaItem{
+				Type:         typ,
+				Name:         ldString(v["name"]),
+				Description:  ldString(v["description"]),
+				URL:          absURL(base, ldString(v["url"])),
+				ThumbnailURL: absURL(base, ldString(v["thumbnailUrl"])),
+				ContentURL:   absURL(base, ldString(v["contentUrl"])),
+				StartDate:    parseSchemaDate(ldString(v["startDate"])),
+			}
+			si.Duration, _ = parseISODuration(ldString(v["duration"]))
+			s.Items = append(s.Items, &si)
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			if k != "@context" {
+				switch e := v[k]; e.(type) {
+				case []any, map[string]any:
+					s.addJSONLD(e, base)
+				}
+			}
+		}
+	}
+}
+
+// addJSONLDBreadcrumbs adds the breadcrumbs of a JSON-LD BreadcrumbList.
+func (s *Schema) addJSONLDBreadcrumbs(v map[string]any, base string) {
+	list, _ := v["itemListElement"].([]any)
+	for _, e := range list {
+		e, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		b := Breadcrumb{Title: ldString(e["name"])}
+		switch item := e["item"].(type) {
+		case string:
+			b.URL = item
+		case map[string]any:
+			b.URL = ldString(item["@id"])
+			if b.Title == "" {
+				b.Title = ldString(item["name"])
+			}
+		}
+		b.URL = absURL(base, b.URL)
+
+		s.Breadcrumbs = append(s.Breadcrumbs, b)
+	}
+}
+
+// ldString returns JSON-LD value v as a string. Of a list, the first value is
+// returned.
+func ldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		if len(v) > 0 {
+			return ldString(v[0])
+		}
+	}
+	return ""
+}
+
+// parseSchemaDate parses an ISO 8601 date, or the date layout of broadcast
+// pages. It returns the zero time if s is not a date.
+func parseSchemaDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339, broadcastDateLayout} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// absURL resolves u against base if it is a path.
+func absURL(base, u string) string {
+	switch {
+	case strings.HasPrefix(u, "//"):
+		return "http:" + u
+	case strings.HasPrefix(u, "/"):
+		return base + u
+	}
+	return u
+}
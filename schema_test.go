@@ -0,0 +1,156 @@
+package gemist
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/xmlpath.v2"
+)
+
+func TestParseSchema_microdata(t *testing.T) {
+	assert := assert.New(t)
+
+	n, err := xmlpath.ParseHTML(strings.NewReader(testDataBroadcastVideo))
+	require.NoError(t, err)
+
+	s := parseSchema(n, DefaultBaseURL)
+	require.NotNil(t, s)
+
+	if series := s.Item("TVSeries"); assert.NotNil(series, "series not found") {
+		assert.Equal("http://schema.org/TVSeries", series.Type)
+		assert.Equal("ZEMBLA", series.Name)
+		assert.Equal("http://www.npo.nl/zembla/POMS_S_VARA_099718", series.URL)
+		assert.True(strings.HasPrefix(series.Description, "ZEMBLA is het actuele documentaireprogramma"), "series description not equal")
+	}
+
+	if episode := s.Item("TVEpisode"); assert.NotNil(episode, "episode not found") {
+		assert.Equal("Het clusterbom gevoel", episode.Name)
+		assert.Equal("http://www.npo.nl/zembla/18-03-2007/VARA_101141965", episode.URL)
+		assert.True(strings.HasPrefix(episode.Description, "Nederlandse pensioenfondsen"), "episode description not equal")
+	}
+
+	if event := s.Item("BroadcastEvent"); assert.NotNil(event, "event not found") {
+		assert.True(time.Date(2007, time.March, 18, 19, 35, 0, 0, time.UTC).Equal(event.StartDate), "start date not equal (%v)", event.StartDate)
+	}
+
+	if video := s.Item("VideoObject", "AudioObject"); assert.NotNil(video, "video not found") {
+		assert.Equal("Zembla", video.Name)
+		assert.Equal("http://images.poms.omroep.nl/image/s174/c174x98/6848.png", video.ThumbnailURL)
+		assert.Equal(50*time.Minute, video.Duration)
+	}
+
+	assert.Equal([]Breadcrumb{
+		{Title: "NPO", URL: "http://www.npo.nl/"},
+		{Title: "ZEMBLA", URL: "http://www.npo.nl/zembla/POMS_S_VARA_099718"},
+		{Title: "Het clusterbom gevoel", URL: "http://www.npo.nl/zembla/18-03-2007/VARA_101141965"},
+	}, s.Breadcrumbs)
+
+	assert.Nil(s.Item("RadioSeries"))
+	assert.Nil((*Schema)(nil).Item("TVSeries"))
+}
+
+func TestParseSchema_jsonLD(t *testing.T) {
+	assert := assert.New(t)
+
+	n, err := xmlpath.ParseHTML(strings.NewReader(`<html><head>
+<script type="application/ld+json">{
+	"@context": "http://schema.org",
+	"@graph": [
+		{
+			"@type": "RadioEpisode",
+			"name": "Radio bergeijk",
+			"url": "/radio-bergeijk/03-04-2001/POMS_VPRO_396139",
+			"thumbnailUrl": ["//images.poms.omroep.nl/image/215303.png"],
+			"partOfSeries": {"@type": "RadioSeries", "name": "Radio Bergeijk", "url": "/radio-bergeijk/POMS_S_VPRO_396280"},
+			"publication": {"@type": "BroadcastEvent", "startDate": "2001-04-03T00:44:00+02:00"},
+			"associatedMedia": {"@type": "AudioObject", "duration": "PT14M45S", "contentUrl": "http://download.omroep.nl/vpro/POMS_VPRO_396139.mp3"}
+		},
+		{
+			"@type": "BreadcrumbList",
+			"itemListElement": [
+				{"@type": "ListItem", "position": 1, "name": "NPO", "item": "/"},
+				{"@type": "ListItem", "position": 2, "item": {"@id": "/radio-bergeijk/POMS_S_VPRO_396280", "name": "Radio Bergeijk"}}
+			]
+		}
+	]
+}</script>
+<script type="application/ld+json">{ invalid</script>
+</head><body></body></html>`))
+	require.NoError(t, err)
+
+	s := parseSchema(n, DefaultBaseURL)
+	require.NotNil(t, s)
+
+	var types []string
+	for _, si := range s.Items {
+		types = append(types, si.Type)
+	}
+	assert.Equal([]string{"RadioEpisode", "AudioObject", "RadioSeries", "BroadcastEvent"}, types)
+
+	if episode := s.Item("RadioEpisode"); assert.NotNil(episode) {
+		assert.Equal("Radio bergeijk", episode.Name)
+		assert.Equal("http://www.npo.nl/radio-bergeijk/03-04-2001/POMS_VPRO_396139", episode.URL)
+		assert.Equal("http://images.poms.omroep.nl/image/215303.png", episode.ThumbnailURL)
+	}
+	if audio := s.Item("AudioObject"); assert.NotNil(audio) {
+		assert.Equal(14*time.Minute+45*time.Second, audio.Duration)
+		assert.Equal("http://download.omroep.nl/vpro/POMS_VPRO_396139.mp3", audio.ContentURL)
+	}
+	assert.False(s.Item("BroadcastEvent").StartDate.IsZero(), "start date not parsed")
+
+	assert.Equal([]Breadcrumb{
+		{Title: "NPO", URL: "http://www.npo.nl/"},
+		{Title: "Radio Bergeijk", URL: "http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280"},
+	}, s.Breadcrumbs)
+}
+
+func TestParseSchema_none(t *testing.T) {
+	n, err := xmlpath.ParseHTML(strings.NewReader(`<html><body><p>Geen schema</p></body></html>`))
+	require.NoError(t, err)
+	assert.Nil(t, parseSchema(n, DefaultBaseURL))
+}
+
+func TestParseBroadcast_schemaFallback(t *testing.T) {
+	l, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err, "error loading time location")
+
+	// Remove the Open Graph tags and move the page layout, so the fields can
+	// only be found in the schema.org microdata.
+	page := testDataBroadcastVideo
+	for _, s := range []string{
+		`<meta content="Het clusterbom gevoel - ZEMBLA" name="og:title" />`,
+		`<meta content="video.episode" name="og:type" />`,
+		`<meta content="3000" name="og:video:duration" />`,
+	} {
+		require.Contains(t, page, s)
+		page = strings.Replace(page, s, "", 1)
+	}
+	page = strings.Replace(page, `<span content="2007-03-18T20:35:00+01:00" itemprop="startDate">2007-03-18 20:35:00 +0100</span>`,
+		`<meta content="2007-03-18T20:35:00+01:00" itemprop="startDate" />`, 1)
+	page = strings.Replace(page, "<div class='content'>\n", "<div class='summary'>\n", 1)
+
+	b, err := ParseBroadcast(strings.NewReader(page))
+	require.NoError(t, err)
+
+	assert := assert.New(t)
+	assert.Equal("Het clusterbom gevoel", b.Title)
+	assert.True(strings.HasPrefix(b.LongDescription, "Nederlandse pensioenfondsen"), "long description not equal")
+	assert.True(time.Date(2007, time.March, 18, 20, 35, 0, 0, l).Equal(b.Date), "date not equal (%v)", b.Date)
+	assert.Equal(Video, b.Type)
+	assert.Equal(50*time.Minute, b.Length)
+	assert.NotNil(b.Schema)
+}
+
+func TestParseBroadcast_schemaFallbackInvalid(t *testing.T) {
+	// An invalid length on the page is an error, even though the schema.org
+	// microdata has a valid one.
+	page := strings.Replace(testDataBroadcastVideo, `<meta content="3000" name="og:video:duration" />`,
+		`<meta content="vijftig minuten" name="og:video:duration" />`, 1)
+	require.Contains(t, page, `<meta content="PT50M0S" itemprop="duration" />`)
+
+	_, err := ParseBroadcast(strings.NewReader(page))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,187 @@
+package gemist
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/xmlpath.v2"
+)
+
+// Segment represents a fragment of a broadcast on Uitzending Gemist.
+type Segment struct {
+	MediaItem
+	Length time.Duration // zero if not listed
+
+	// ParentID is the POMS identifier of the broadcast the segment is part
+	// of and ParentURL the URL of its page.
+	ParentID  MediaID
+	ParentURL string
+}
+
+// Segments returns the segments listed on the program page.
+func (p *Program) Segments() []*Segment {
+	ss := make([]*Segment, len(p.ss))
+	copy(ss, p.ss)
+	return ss
+}
+
+// NumSegments returns the total number of segments of the program, which can
+// be more than are listed on the program page. Use Client.GetSegments to get
+// all of them.
+func (p *Program) NumSegments() int {
+	return p.nss
+}
+
+// GetSegments gets all segments of the program at url by following its
+// archive pages, like GetBroadcasts.
+func (c *Client) GetSegments(ctx context.Context, url string, progress func(ArchiveProgress)) ([]*Segment, error) {
+	var (
+		ss   []*Segment
+		seen = make(map[string]bool)
+	)
+
+	parse := func(n *xmlpath.Node, base string, _ time.Time) (int, int, error) {
+		page, total, err := parseProgramSegments(n, base)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, s := range page {
+			if !seen[s.URL] {
+				seen[s.URL] = true
+				ss = append(ss, s)
+			}
+		}
+		return len(page), total, nil
+	}
+
+	err := c.getArchive(ctx, url, "segment", parse, progress)
+	return ss, err
+}
+
+var pPSData = mustCompile("//div[@id='segments-block']/div[1]/div[1]")
+
+// parseProgramSegments parses the segment list of a program or archive page.
+// It returns the listed segments and the total number of segments.
+func parseProgramSegments(n *xmlpath.Node, base string) ([]*Segment, int, error) {
+	iter := pPSData.Iter(n)
+	if !iter.Next() {
+		return nil, 0, missingError("segment list", pPSData)
+	}
+	l := iter.Node()
+
+	num, err := parseListLength(l, "segment list length")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ss []*Segment
+	iter = pPListItems.Iter(l)
+	for iter.Next() {
+		s, err := parseSegmentListItem(iter.Node(), base)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		ss = append(ss, s)
+	}
+
+	return ss, num, nil
+}
+
+// The description of a segment list item is nested in an anchor with another
+// anchor in its header, so it is looked up anywhere in the item text.
+var pPSListItemDesc = mustCompile("div[2]//p/text()")
+
+func parseSegmentListItem(n *xmlpath.Node, base string) (*Segment, error) {
+	title, ok := pPListItemTitle.String(n)
+	if !ok {
+		return nil, missingError("segment title", pPListItemTitle)
+	}
+
+	// No need to exist, no description is valid.
+	desc, _ := pPSListItemDesc.String(n)
+
+	img, ok := pPListItemImage.String(n)
+	if !ok {
+		return nil, missingError("segment image", pPListItemImage)
+	}
+
+	path, ok := pPListItemURL.String(n)
+	if !ok {
+		return nil, missingError("segment URL", pPListItemURL)
+	}
+
+	lenstr, ok := pPListItemLen.String(n)
+	if !ok {
+		return nil, missingError("segment length", pPListItemLen)
+	}
+
+	len, err := parseBroadcastLength(lenstr)
+	if err != nil {
+		return nil, invalidError("segment length", pPListItemLen, lenstr, err)
+	}
+
+	s := newSegment(base, path, title, desc, img)
+	s.Length = len
+	return s, nil
+}
+
+var (
+	pBSItems     = mustCompile("//div[contains(@class,'program-fragments')]//div[contains(@class,'items-carousel-item')]")
+	pBSItemTitle = mustCompile("div/div[@class='description']/h4/@title")
+	pBSItemImage = mustCompile("div/div[@class='image-container']/a/img/@src")
+	pBSItemURL   = mustCompile("div/div[@class='image-container']/a/@href")
+)
+
+// parseBroadcastSegments parses the segment carousel of a broadcast page. A
+// page without segments has no carousel items.
+func parseBroadcastSegments(n *xmlpath.Node, base string) ([]*Segment, error) {
+	var ss []*Segment
+
+	iter := pBSItems.Iter(n)
+	for iter.Next() {
+		item := iter.Node()
+
+		title, ok := pBSItemTitle.String(item)
+		if !ok {
+			return nil, missingError("segment title", pBSItemTitle)
+		}
+
+		img, _ := pBSItemImage.String(item)
+
+		path, ok := pBSItemURL.String(item)
+		if !ok {
+			return nil, missingError("segment URL", pBSItemURL)
+		}
+
+		ss = append(ss, newSegment(base, path, title, "", img))
+	}
+
+	return ss, nil
+}
+
+// newSegment returns a segment for the page at path. Segment pages are at
+// /<slug>/<date>/<broadcast ID>/<segment ID>, so the parent broadcast is
+// found by dropping the last path element.
+func newSegment(base, p, title, desc, img string) *Segment {
+	var imgs []string
+	if img != "" {
+		imgs = []string{img}
+	}
+
+	parent := path.Dir(p)
+
+	return &Segment{
+		MediaItem: MediaItem{
+			Title:       strings.TrimSpace(title),
+			Description: strings.TrimSpace(desc),
+			ImageURLs:   imgs,
+			URL:         base + p,
+		},
+		ParentID:  MediaID(path.Base(parent)),
+		ParentURL: base + parent,
+	}
+}
@@ -0,0 +1,187 @@
+package gemist
+
+import (
+	"context"
+	"strings"
+)
+
+// A StreamPolicy chooses between the streams of a broadcast.
+type StreamPolicy struct {
+	audioOnly bool
+	worst     bool
+	maxHeight int
+	maxRate   int
+}
+
+// Stream policies.
+var (
+	// Best selects the stream with the highest bitrate.
+	Best = StreamPolicy{}
+
+	// Worst selects the stream with the lowest bitrate.
+	Worst = StreamPolicy{worst: true}
+
+	// AudioOnly selects the audio-only stream with the highest bitrate.
+	AudioOnly = StreamPolicy{audioOnly: true}
+)
+
+// MaxHeight returns a policy that selects the stream with the highest bitrate
+// of the video streams at most h pixels high.
+func MaxHeight(h int) StreamPolicy {
+	return StreamPolicy{maxHeight: h}
+}
+
+// MaxBandwidth returns a policy that selects the stream with the highest
+// bitrate not exceeding bps bits per second.
+func MaxBandwidth(bps int) StreamPolicy {
+	return StreamPolicy{maxRate: bps}
+}
+
+func (p StreamPolicy) allows(s Stream) bool {
+	switch {
+	case p.audioOnly && !s.IsAudioOnly():
+		return false
+	case p.maxHeight > 0 && (s.Height == 0 || s.Height > p.maxHeight):
+		return false
+	case p.maxRate > 0 && (s.Bitrate == 0 || s.Bitrate > p.maxRate):
+		return false
+	}
+	return true
+}
+
+// better reports whether s ranks before t. Streams are ranked by bitrate,
+// then by height, with unknown bitrates last. Earlier streams win ties.
+func (p StreamPolicy) better(s, t Stream) bool {
+	if (s.Bitrate == 0) != (t.Bitrate == 0) {
+		return t.Bitrate == 0
+	}
+	if s.Bitrate != t.Bitrate {
+		return (s.Bitrate > t.Bitrate) != p.worst
+	}
+	if s.Height != t.Height {
+		return (s.Height > t.Height) != p.worst
+	}
+	return false
+}
+
+// SelectStream selects a stream out of ss according to policy p. The
+// selection only depends on the streams and their order. ErrNoStreams is
+// returned if no stream is allowed by p.
+func SelectStream(ss []Stream, p StreamPolicy) (Stream, error) {
+	var (
+		best  Stream
+		found bool
+	)
+
+	for _, s := range ss {
+		if !p.allows(s) {
+			continue
+		}
+		if !found || p.better(s, best) {
+			best, found = s, true
+		}
+	}
+
+	if !found {
+		return Stream{}, ErrNoStreams
+	}
+	return best, nil
+}
+
+// SelectStream selects one of the resolved streams of the broadcast according
+// to policy p.
+func (b *Broadcast) SelectStream(p StreamPolicy) (Stream, error) {
+	return SelectStream(b.Streams, p)
+}
+
+var audioCodecs = []string{"mp4a", "mp3", "ac-3", "ec-3", "opus"}
+
+var audioContainers = map[string]bool{
+	"mp3": true,
+	"aac": true,
+	"m4a": true,
+}
+
+// IsAudioOnly reports whether the stream carries no video.
+func (s Stream) IsAudioOnly() bool {
+	if s.Width != 0 || s.Height != 0 {
+		return false
+	}
+
+	if len(s.Codecs) == 0 {
+		return audioContainers[s.Container]
+	}
+
+	for _, c := range s.Codecs {
+		audio := false
+		for _, ac := range audioCodecs {
+			if strings.HasPrefix(c, ac) {
+				audio = true
+				break
+			}
+		}
+		if !audio {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveVariants adds the variants of the HLS master playlists among the
+// resolved streams of b to b.Streams, so they can be selected individually.
+// HLS media playlists have no variants and add nothing. Streams already in
+// b.Streams are not added again.
+func (c *Client) ResolveVariants(ctx context.Context, b *Broadcast) error {
+	ss := b.Streams
+
+	seen := make(map[string]bool)
+	for _, s := range b.Streams {
+		seen[s.URL] = true
+	}
+
+	for _, s := range b.Streams {
+		if s.Protocol != ProtocolHLS {
+			continue
+		}
+
+		p, err := c.getMasterPlaylist(ctx, s.URL)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range p.Variants {
+			if seen[v.URI] {
+				continue
+			}
+			seen[v.URI] = true
+
+			vs := Stream{
+				URL:       v.URI,
+				Protocol:  ProtocolHLS,
+				Container: s.Container,
+				Bitrate:   v.Bandwidth,
+				Width:     v.Width,
+				Height:    v.Height,
+				Codecs:    v.Codecs,
+				Expires:   s.Expires,
+			}
+			if vs.IsAudioOnly() {
+				vs.Container = "aac" // see Downloader.DownloadHLS
+			}
+			ss = append(ss, vs)
+		}
+	}
+
+	b.Streams = ss
+	return nil
+}
+
+func (c *Client) getMasterPlaylist(ctx context.Context, url string) (*MasterPlaylist, error) {
+	r, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	return ParseMasterPlaylist(r.Body, r.Request.URL.String())
+}
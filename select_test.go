@@ -0,0 +1,83 @@
+package gemist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testStreams = []Stream{
+	{URL: "adaptive", Protocol: ProtocolHLS, Container: "ts"},
+	{URL: "h264_std", Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 1000000, Width: 1024, Height: 576},
+	{URL: "h264_bb", Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 500000, Width: 640, Height: 360},
+	{URL: "hls_bb", Protocol: ProtocolHLS, Container: "ts", Bitrate: 500000, Width: 640, Height: 360},
+	{URL: "h264_sb", Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 200000, Width: 320, Height: 180},
+	{URL: "audio", Protocol: ProtocolHLS, Container: "ts", Bitrate: 128000, Codecs: []string{"mp4a.40.2"}},
+}
+
+func TestSelectStream(t *testing.T) {
+	tests := []struct {
+		p   StreamPolicy
+		url string
+	}{
+		{Best, "h264_std"},
+		{Worst, "audio"},
+		{MaxHeight(360), "h264_bb"},
+		{MaxHeight(400), "h264_bb"},
+		{MaxHeight(100), ""},
+		{MaxBandwidth(600000), "h264_bb"},
+		{MaxBandwidth(100000), ""},
+		{AudioOnly, "audio"},
+	}
+
+	for _, tt := range tests {
+		s, err := SelectStream(testStreams, tt.p)
+		if tt.url == "" {
+			assert.ErrorIs(t, err, ErrNoStreams, "%+v", tt.p)
+			continue
+		}
+		if assert.NoError(t, err, "%+v", tt.p) {
+			assert.Equal(t, tt.url, s.URL, "%+v", tt.p)
+		}
+	}
+
+	b := Broadcast{Streams: []Stream{{URL: "http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3", Protocol: ProtocolHTTP, Container: "mp3"}}}
+	s, err := b.SelectStream(AudioOnly)
+	assert.NoError(t, err)
+	assert.Equal(t, b.Streams[0], s)
+}
+
+func TestClient_ResolveVariants(t *testing.T) {
+	assert := assert.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testDataMasterPlaylist)
+	}))
+	defer s.Close()
+
+	b := Broadcast{Streams: []Stream{{URL: s.URL + "/vara/adaptive/VARA_101141965.m3u8", Protocol: ProtocolHLS, Container: "ts"}}}
+
+	var c Client
+	assert.NoError(c.ResolveVariants(context.Background(), &b))
+	if assert.Len(b.Streams, 4, "variants not added") {
+		assert.Equal(Stream{
+			URL:       s.URL + "/vara/adaptive/VARA_101141965-video=600000.m3u8",
+			Protocol:  ProtocolHLS,
+			Container: "ts",
+			Bitrate:   700000,
+			Width:     640,
+			Height:    360,
+			Codecs:    []string{"avc1.4d401e", "mp4a.40.2"},
+		}, b.Streams[2], "variant stream not equal")
+	}
+
+	st, err := b.SelectStream(AudioOnly)
+	if assert.NoError(err) {
+		assert.Equal("http://e.omroep.nl/vara/audio/VARA_101141965-audio=128000.m3u8", st.URL)
+		assert.Equal("aac", st.Container)
+	}
+}
@@ -0,0 +1,14 @@
+package gemist
+
+import "gopkg.in/xmlpath.v2"
+
+// A selector is a compiled xmlpath expression that remembers its source, so
+// it can be reported in parse errors.
+type selector struct {
+	*xmlpath.Path
+	expr string
+}
+
+func mustCompile(expr string) *selector {
+	return &selector{Path: xmlpath.MustCompile(expr), expr: expr}
+}
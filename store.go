@@ -0,0 +1,325 @@
+package gemist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Store persists programs and broadcasts, keyed by media ID, together with
+// when they were fetched and whether they were downloaded. Getting a record
+// that is not stored returns ErrNotFound.
+type Store interface {
+	// PutProgram stores p, and the broadcasts listed on its page as
+	// broadcast records of the program.
+	PutProgram(p *Program) error
+	Program(id MediaID) (*ProgramRecord, error)
+
+	// PutBroadcast stores b as a broadcast of the program with the given
+	// ID, which may be empty if it is unknown.
+	PutBroadcast(program MediaID, b *Broadcast) error
+	Broadcast(id MediaID) (*BroadcastRecord, error)
+
+	// SetDownload sets the download state of the broadcast with the given
+	// ID, which must be stored.
+	SetDownload(id MediaID, d DownloadRecord) error
+
+	// Broadcasts returns the stored broadcasts matching q, oldest first.
+	Broadcasts(q Query) ([]*BroadcastRecord, error)
+}
+
+// A ProgramRecord is a stored program.
+type ProgramRecord struct {
+	ID        MediaID   `json:"id"`
+	Program   *Program  `json:"program"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// A BroadcastRecord is a stored broadcast. It holds the broadcast as listed on
+// its program page, its parsed broadcast page or both.
+type BroadcastRecord struct {
+	ID        MediaID         `json:"id"`
+	ProgramID MediaID         `json:"program_id"`
+	Proxy     *BroadcastProxy `json:"proxy,omitempty"`
+	ListedAt  time.Time       `json:"listed_at"` // when Proxy was fetched
+	Broadcast *Broadcast      `json:"broadcast,omitempty"`
+	FetchedAt time.Time       `json:"fetched_at"` // when Broadcast was fetched
+	Download  DownloadRecord  `json:"download"`
+}
+
+// Date returns the date of the broadcast, from its page if it was fetched.
+func (r *BroadcastRecord) Date() time.Time {
+	if r.Broadcast != nil {
+		return r.Broadcast.Date
+	}
+	if r.Proxy != nil {
+		return r.Proxy.Date
+	}
+	return time.Time{}
+}
+
+// DownloadState is the state of the download of a broadcast.
+type DownloadState string
+
+// Download states.
+const (
+	NotDownloaded    DownloadState = ""
+	DownloadPartial  DownloadState = "partial"
+	DownloadComplete DownloadState = "complete"
+	DownloadFailed   DownloadState = "failed"
+)
+
+// A DownloadRecord records the download of a broadcast.
+type DownloadRecord struct {
+	State     DownloadState `json:"state"`
+	File      string        `json:"file,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// A Query selects stored broadcasts. The zero Query selects all of them.
+type Query struct {
+	Program MediaID // only broadcasts of the program, if set
+
+	// Only broadcasts dated in [After, Before). A zero time means no bound,
+	// like Between.
+	After, Before time.Time
+
+	NotDownloaded bool // only broadcasts without a complete download
+}
+
+func (q Query) match(r *BroadcastRecord) bool {
+	if q.Program != "" && r.ProgramID != q.Program {
+		return false
+	}
+
+	d := r.Date()
+	if !q.After.IsZero() && d.Before(q.After) {
+		return false
+	}
+	if !q.Before.IsZero() && !d.Before(q.Before) {
+		return false
+	}
+
+	return !q.NotDownloaded || r.Download.State != DownloadComplete
+}
+
+// FileStore is a Store that keeps every record in a JSON file in a directory
+// tree:
+//
+//	<dir>/programs/<id>.json
+//	<dir>/broadcasts/<id>.json
+//
+// Records are encoded with the JSON representation of this package, and are
+// written atomically. A FileStore is safe for concurrent use within a
+// process, but not by multiple processes.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a FileStore in dir, which is created if it does not
+// exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{"programs", "broadcasts"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// PutProgram implements Store.
+func (s *FileStore) PutProgram(p *Program) error {
+	id := p.ID()
+	if err := checkStoreID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err := s.write("programs", id, &ProgramRecord{ID: id, Program: p, FetchedAt: now}); err != nil {
+		return err
+	}
+
+	for _, bp := range p.bs {
+		bid := bp.ID()
+		if !bid.Valid() {
+			continue
+		}
+
+		r, err := s.broadcast(bid)
+		if err != nil {
+			return err
+		}
+		r.ProgramID = id
+		r.Proxy = bp
+		r.ListedAt = now
+
+		if err := s.write("broadcasts", bid, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Program implements Store.
+func (s *FileStore) Program(id MediaID) (*ProgramRecord, error) {
+	if err := checkStoreID(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r ProgramRecord
+	if err := s.read(filepath.Join(s.dir, "programs", string(id)+".json"), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// PutBroadcast implements Store.
+func (s *FileStore) PutBroadcast(program MediaID, b *Broadcast) error {
+	id := b.ID()
+	if err := checkStoreID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.broadcast(id)
+	if err != nil {
+		return err
+	}
+	if program != "" {
+		r.ProgramID = program
+	}
+	r.Broadcast = b
+	r.FetchedAt = time.Now()
+
+	return s.write("broadcasts", id, r)
+}
+
+// Broadcast implements Store.
+func (s *FileStore) Broadcast(id MediaID) (*BroadcastRecord, error) {
+	if err := checkStoreID(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r BroadcastRecord
+	if err := s.read(filepath.Join(s.dir, "broadcasts", string(id)+".json"), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// SetDownload implements Store. If d.UpdatedAt is zero, the current time is
+// used.
+func (s *FileStore) SetDownload(id MediaID, d DownloadRecord) error {
+	if err := checkStoreID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r BroadcastRecord
+	if err := s.read(filepath.Join(s.dir, "broadcasts", string(id)+".json"), &r); err != nil {
+		return err
+	}
+
+	if d.UpdatedAt.IsZero() {
+		d.UpdatedAt = time.Now()
+	}
+	r.Download = d
+
+	return s.write("broadcasts", id, &r)
+}
+
+// Broadcasts implements Store. It reads all broadcast records.
+func (s *FileStore) Broadcasts(q Query) ([]*BroadcastRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := filepath.Glob(filepath.Join(s.dir, "broadcasts", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var rs []*BroadcastRecord
+	for _, name := range names {
+		var r BroadcastRecord
+		if err := s.read(name, &r); err != nil {
+			return nil, err
+		}
+		if q.match(&r) {
+			rs = append(rs, &r)
+		}
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Date().Before(rs[j].Date())
+	})
+
+	return rs, nil
+}
+
+// broadcast returns the stored record of the broadcast with the given ID, or
+// a new record if it is not stored.
+func (s *FileStore) broadcast(id MediaID) (*BroadcastRecord, error) {
+	var r BroadcastRecord
+	err := s.read(filepath.Join(s.dir, "broadcasts", string(id)+".json"), &r)
+	if errors.Is(err, ErrNotFound) {
+		return &BroadcastRecord{ID: id}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *FileStore) read(name string, v interface{}) error {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("gemist: reading %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *FileStore) write(kind string, id MediaID, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(s.dir, kind, string(id)+".json"), data)
+}
+
+// checkStoreID checks that id can be used as the key of a record.
+func checkStoreID(id MediaID) error {
+	if !id.Valid() {
+		return fmt.Errorf("gemist: invalid media ID %q", id)
+	}
+	return nil
+}
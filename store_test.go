@@ -0,0 +1,114 @@
+package gemist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	var _ Store = s
+
+	p, err := ParseProgram(strings.NewReader(testProgramPage(3, 2, 1)))
+	require.NoError(t, err)
+	require.NoError(t, s.PutProgram(p))
+	assert.FileExists(t, filepath.Join(dir, "programs", "POMS_S_VPRO_396280.json"))
+
+	pr, err := s.Program("POMS_S_VPRO_396280")
+	require.NoError(t, err)
+	assert.Equal(t, p.MediaItem, pr.Program.MediaItem)
+	assert.Equal(t, 3, pr.Program.NumBroadcasts())
+	require.Len(t, pr.Program.Broadcasts(), 3)
+	assert.True(t, p.bs[0].Date.Equal(pr.Program.bs[0].Date))
+	assert.WithinDuration(t, time.Now(), pr.FetchedAt, time.Minute)
+
+	r, err := s.Broadcast("POMS_VPRO_1002")
+	require.NoError(t, err)
+	assert.Equal(t, MediaID("POMS_S_VPRO_396280"), r.ProgramID)
+	assert.Equal(t, "Aflevering 2", r.Proxy.Title)
+	assert.Nil(t, r.Broadcast)
+	assert.Equal(t, NotDownloaded, r.Download.State)
+
+	b := &Broadcast{
+		MediaItem: MediaItem{Title: "Aflevering 2", URL: r.Proxy.URL},
+		Date:      r.Proxy.Date,
+		Type:      Audio,
+	}
+	require.NoError(t, s.PutBroadcast("", b))
+	require.NoError(t, s.SetDownload("POMS_VPRO_1002", DownloadRecord{State: DownloadComplete, File: "POMS_VPRO_1002.mp3"}))
+
+	r, err = s.Broadcast("POMS_VPRO_1002")
+	require.NoError(t, err)
+	assert.Equal(t, MediaID("POMS_S_VPRO_396280"), r.ProgramID)
+	assert.Equal(t, "Aflevering 2", r.Proxy.Title)
+	assert.Equal(t, Audio, r.Broadcast.Type)
+	assert.Equal(t, DownloadComplete, r.Download.State)
+	assert.False(t, r.Download.UpdatedAt.IsZero())
+
+	// Re-listing the program keeps the broadcast and download state.
+	require.NoError(t, s.PutProgram(p))
+	r, err = s.Broadcast("POMS_VPRO_1002")
+	require.NoError(t, err)
+	assert.NotNil(t, r.Broadcast)
+	assert.Equal(t, DownloadComplete, r.Download.State)
+
+	// A store reopened on the same directory sees the same records.
+	s, err = NewFileStore(dir)
+	require.NoError(t, err)
+
+	rs, err := s.Broadcasts(Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []MediaID{"POMS_VPRO_1001", "POMS_VPRO_1002", "POMS_VPRO_1003"}, recordIDs(rs))
+
+	rs, err = s.Broadcasts(Query{NotDownloaded: true})
+	require.NoError(t, err)
+	assert.Equal(t, []MediaID{"POMS_VPRO_1001", "POMS_VPRO_1003"}, recordIDs(rs))
+
+	rs, err = s.Broadcasts(Query{
+		Program: "POMS_S_VPRO_396280",
+		After:   time.Date(2007, 10, 2, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []MediaID{"POMS_VPRO_1002", "POMS_VPRO_1003"}, recordIDs(rs))
+
+	rs, err = s.Broadcasts(Query{Program: "POMS_S_VPRO_1"})
+	require.NoError(t, err)
+	assert.Empty(t, rs)
+}
+
+func TestFileStore_errors(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	_, err = s.Program("POMS_S_VPRO_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Broadcast("POMS_VPRO_1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, s.SetDownload("POMS_VPRO_1", DownloadRecord{}), ErrNotFound)
+
+	_, err = s.Broadcast("../../etc/passwd")
+	assert.Error(t, err)
+	assert.Error(t, s.PutBroadcast("", &Broadcast{MediaItem: MediaItem{URL: "http://www.npo.nl/x"}}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broadcasts", "POMS_VPRO_2.json"), []byte("{"), 0644))
+	_, err = s.Broadcasts(Query{})
+	assert.Error(t, err)
+}
+
+func recordIDs(rs []*BroadcastRecord) []MediaID {
+	var ids []MediaID
+	for _, r := range rs {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
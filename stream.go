@@ -0,0 +1,263 @@
+package gemist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol is the protocol a stream is delivered with.
+type Protocol string
+
+// Stream protocols.
+const (
+	ProtocolHTTP Protocol = "http" // progressive download of a single file
+	ProtocolHLS  Protocol = "hls"  // HTTP Live Streaming playlist
+)
+
+// Stream represents a location a broadcast can be streamed or downloaded from.
+type Stream struct {
+	URL       string
+	Protocol  Protocol
+	Container string // file format, e.g. "mp3", "mp4" or "ts"
+	Bitrate   int    // bits per second, zero if unknown
+	Width     int    // zero if unknown or audio
+	Height    int    // zero if unknown or audio
+	Codecs    []string
+	Expires   time.Time
+}
+
+// Expired reports whether the stream URL has expired at t. A stream without
+// expiry never expires.
+func (s Stream) Expired(t time.Time) bool {
+	return !s.Expires.IsZero() && !t.Before(s.Expires)
+}
+
+// ErrNoStreams is returned when no streams are available for a broadcast,
+// for example because it is geo-blocked or expired.
+var ErrNoStreams = errors.New("gemist: no streams available")
+
+// DefaultPlayerURL is the base URL of the NPO player API.
+const DefaultPlayerURL = "http://ida.omroep.nl"
+
+// ResolveStreams resolves the stream locations of the broadcast into
+// b.Streams. It uses DefaultClient.
+func (b *Broadcast) ResolveStreams(ctx context.Context) error {
+	return DefaultClient.ResolveStreams(ctx, b)
+}
+
+// ResolveStreams resolves the stream locations of the broadcast into
+// b.Streams. Audio broadcasts are streamed from their media URL. Video
+// broadcasts are looked up with the NPO player API, which returns an HLS
+// stream and progressive downloads in several qualities.
+func (c *Client) ResolveStreams(ctx context.Context, b *Broadcast) error {
+	if b.Type.IsAudio() {
+		b.Streams = []Stream{{
+			URL:       b.MediaURL,
+			Protocol:  ProtocolHTTP,
+			Container: containerOf(b.MediaURL, "mp3"),
+		}}
+		return nil
+	}
+
+	id := b.ID()
+	if id == "" {
+		return fmt.Errorf("gemist: no media ID in broadcast URL %s", b.URL)
+	}
+
+	token, err := c.playerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ss []Stream
+	for _, opt := range pubOptions {
+		s, ok, err := c.playerStream(ctx, id, opt, token)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ss = append(ss, s)
+		}
+	}
+
+	if len(ss) == 0 {
+		return ErrNoStreams
+	}
+
+	b.Streams = ss
+	return nil
+}
+
+// A pubOption is a publication option of the NPO player API.
+type pubOption struct {
+	name string
+	s    Stream // nominal properties of streams of this option
+}
+
+var pubOptions = []pubOption{
+	{"adaptive", Stream{Protocol: ProtocolHLS, Container: "ts"}},
+	{"h264_std", Stream{Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 1000000, Width: 1024, Height: 576}},
+	{"h264_bb", Stream{Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 500000, Width: 640, Height: 360}},
+	{"h264_sb", Stream{Protocol: ProtocolHTTP, Container: "mp4", Bitrate: 200000, Width: 320, Height: 180}},
+}
+
+func (c *Client) playerURL() string {
+	if c.PlayerURL != "" {
+		return strings.TrimSuffix(c.PlayerURL, "/")
+	}
+	return DefaultPlayerURL
+}
+
+var playerTokenRegexp = regexp.MustCompile(`npoplayer\.token = "(.+?)"`)
+
+// playerToken requests a token for the player API.
+func (c *Client) playerToken(ctx context.Context) (string, error) {
+	body, _, err := c.getBytes(ctx, c.playerURL()+"/npoplayer/i.js")
+	if err != nil {
+		return "", err
+	}
+
+	parts := playerTokenRegexp.FindSubmatch(body)
+	if parts == nil {
+		return "", errors.New("gemist: no token in player script")
+	}
+
+	return unscrambleToken(string(parts[1])), nil
+}
+
+// unscrambleToken reverses the scrambling the player script applies to the
+// token: the first two digits between the fifth and fifth-last character are
+// swapped, or the characters at 12 and 13 if there are no two such digits.
+func unscrambleToken(token string) string {
+	t := []byte(token)
+
+	first, second := -1, -1
+	for i := 5; i < len(t)-4; i++ {
+		if t[i] >= '0' && t[i] <= '9' {
+			if first < 0 {
+				first = i
+			} else if second < 0 {
+				second = i
+			}
+		}
+	}
+	if first < 0 || second < 0 {
+		first, second = 12, 13
+	}
+	if second >= len(t) {
+		return token
+	}
+
+	t[first], t[second] = t[second], t[first]
+	return string(t)
+}
+
+type playerResponse struct {
+	ErrorCode   int      `json:"errorcode"`
+	ErrorString string   `json:"errorstring"`
+	Streams     []string `json:"streams"`
+	URL         string   `json:"url"`
+}
+
+// playerStream looks up the stream of publication option opt. It reports
+// false if the option is not available for the broadcast.
+func (c *Client) playerStream(ctx context.Context, id MediaID, opt pubOption, token string) (Stream, bool, error) {
+	q := url.Values{
+		"prid":       {string(id)},
+		"puboptions": {opt.name},
+		"adaptive":   {"yes"},
+		"token":      {token},
+	}
+
+	var odi playerResponse
+	err := c.getJSON(ctx, c.playerURL()+"/odi/?"+q.Encode(), &odi)
+	if err != nil {
+		return Stream{}, false, err
+	}
+	if odi.ErrorCode != 0 || len(odi.Streams) == 0 {
+		return Stream{}, false, nil
+	}
+
+	su, err := url.Parse(odi.Streams[0])
+	if err != nil {
+		return Stream{}, false, err
+	}
+	sq := su.Query()
+	sq.Set("type", "json")
+	su.RawQuery = sq.Encode()
+
+	var info playerResponse
+	err = c.getJSON(ctx, su.String(), &info)
+	if err != nil {
+		return Stream{}, false, err
+	}
+	if info.ErrorCode != 0 || info.URL == "" {
+		return Stream{}, false, nil
+	}
+
+	s := opt.s
+	s.URL = info.URL
+	s.Expires = streamExpiry(info.URL)
+	return s, true, nil
+}
+
+// getJSON requests rawurl and decodes the JSON or JSONP response into v.
+func (c *Client) getJSON(ctx context.Context, rawurl string, v interface{}) error {
+	body, _, err := c.getBytes(ctx, rawurl)
+	if err != nil {
+		return err
+	}
+
+	// Strip a JSONP callback.
+	if i, j := bytes.IndexByte(body, '{'), bytes.LastIndexByte(body, '}'); i > 0 && j > i {
+		body = body[i : j+1]
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+var akamaiExpiryRegexp = regexp.MustCompile(`(?:^|~)exp=(\d+)`)
+
+// streamExpiry returns the expiry of an Akamai token in the query of a stream
+// URL, or the zero time if there is none.
+func streamExpiry(rawurl string) time.Time {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return time.Time{}
+	}
+
+	q := u.Query()
+	for _, k := range []string{"hdnts", "hdnea", "__gda__"} {
+		parts := akamaiExpiryRegexp.FindStringSubmatch(q.Get(k))
+		if parts == nil {
+			continue
+		}
+		if sec, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+
+	return time.Time{}
+}
+
+// containerOf returns the file extension of the URL path, or def if it has
+// none.
+func containerOf(rawurl, def string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return def
+	}
+
+	if i := strings.LastIndexByte(u.Path, '.'); i >= 0 && i > strings.LastIndexByte(u.Path, '/') {
+		return strings.ToLower(u.Path[i+1:])
+	}
+	return def
+}
@@ -0,0 +1,116 @@
+package gemist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// playerServer is a stand-in for the NPO player API with responses recorded
+// for VARA_101141965. Only the adaptive and h264_bb options are available.
+func playerServer(t *testing.T) *httptest.Server {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/npoplayer/i.js":
+			fmt.Fprint(w, `npoplayer.token = "k2b6i0t3qd5ab7ht0k8mfo9ck6";`)
+		case "/odi/":
+			if got := q.Get("token"); got != "k2b6i3t0qd5ab7ht0k8mfo9ck6" {
+				t.Errorf("token %q not unscrambled", got)
+			}
+			if q.Get("prid") != "VARA_101141965" {
+				t.Errorf("prid %q not equal", q.Get("prid"))
+			}
+			switch q.Get("puboptions") {
+			case "adaptive", "h264_bb":
+				fmt.Fprintf(w, `{"errorcode":0,"errorstring":"","streams":["%s/video/ida/%s/0c1a2b3c?extension=.m3u8"]}`, s.URL, q.Get("puboptions"))
+			default:
+				fmt.Fprint(w, `{"errorcode":1,"errorstring":"Geen streams gevonden","streams":[]}`)
+			}
+		case "/video/ida/adaptive/0c1a2b3c":
+			fmt.Fprint(w, `setSource({"errorcode":0,"url":"http://e.omroep.nl/vara/adaptive/VARA_101141965.m3u8?hdnts=exp=1445438400~acl=/*~hmac=0a1b","family":"adaptive"})`)
+		case "/video/ida/h264_bb/0c1a2b3c":
+			if q.Get("type") != "json" {
+				t.Errorf("stream info requested with type %q", q.Get("type"))
+			}
+			fmt.Fprint(w, `{"errorcode":0,"url":"http://pdvideosdaily.omroep.nl/vara/h264_bb/VARA_101141965.mp4","family":"h264"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func TestClient_ResolveStreams_video(t *testing.T) {
+	assert := assert.New(t)
+
+	s := playerServer(t)
+	defer s.Close()
+
+	b := Broadcast{
+		MediaItem: MediaItem{URL: "http://www.npo.nl/zembla/18-03-2007/VARA_101141965"},
+		Type:      Video,
+		MediaURL:  "http://www.npo.nl/zembla/18-03-2007/VARA_101141965",
+	}
+
+	c := Client{PlayerURL: s.URL}
+	err := c.ResolveStreams(context.Background(), &b)
+	assert.NoError(err)
+	assert.Equal([]Stream{
+		{
+			URL:       "http://e.omroep.nl/vara/adaptive/VARA_101141965.m3u8?hdnts=exp=1445438400~acl=/*~hmac=0a1b",
+			Protocol:  ProtocolHLS,
+			Container: "ts",
+			Expires:   time.Unix(1445438400, 0),
+		},
+		{
+			URL:       "http://pdvideosdaily.omroep.nl/vara/h264_bb/VARA_101141965.mp4",
+			Protocol:  ProtocolHTTP,
+			Container: "mp4",
+			Bitrate:   500000,
+			Width:     640,
+			Height:    360,
+		},
+	}, b.Streams, "streams not equal")
+}
+
+func TestClient_ResolveStreams_audio(t *testing.T) {
+	b := Broadcast{
+		Type:     Audio,
+		MediaURL: "http://download.omroep.nl/vpro/29/08/57/39/POMS_VPRO_396139.mp3",
+	}
+
+	var c Client
+	err := c.ResolveStreams(context.Background(), &b)
+	assert.NoError(t, err)
+	assert.Equal(t, []Stream{{URL: b.MediaURL, Protocol: ProtocolHTTP, Container: "mp3"}}, b.Streams)
+}
+
+func TestClient_ResolveStreams_none(t *testing.T) {
+	s := playerServer(t)
+	defer s.Close()
+
+	b := Broadcast{MediaItem: MediaItem{URL: "http://www.npo.nl/zembla/18-03-2007/VARA_101141965"}, Type: Video}
+	c := Client{PlayerURL: s.URL + "/missing"}
+	err := c.ResolveStreams(context.Background(), &b)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUnscrambleToken(t *testing.T) {
+	assert.Equal(t, "k2b6i3t0qd5ab7ht0k8mfo9ck6", unscrambleToken("k2b6i0t3qd5ab7ht0k8mfo9ck6"))
+	assert.Equal(t, "abcdefghijklnmopqrst", unscrambleToken("abcdefghijklmnopqrst"))
+	assert.Equal(t, "short", unscrambleToken("short"))
+}
+
+func TestStream_Expired(t *testing.T) {
+	s := Stream{Expires: time.Unix(1445438400, 0)}
+	assert.False(t, s.Expired(time.Unix(1445438399, 0)))
+	assert.True(t, s.Expired(time.Unix(1445438400, 0)))
+	assert.False(t, Stream{}.Expired(time.Now()))
+}
@@ -0,0 +1,351 @@
+package gemist
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and waits for it to pass. It allows a Watcher to be
+// tested without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// A SeenStore remembers the episodes a Watcher has seen, by key. The key of an
+// episode is its media ID, or its URL if it has none.
+type SeenStore interface {
+	Seen(key string) (bool, error)
+	MarkSeen(key string) error
+}
+
+// MemorySeenStore is a SeenStore that keeps the seen episodes in memory. The
+// zero value is ready to use.
+type MemorySeenStore struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// Seen implements SeenStore.
+func (s *MemorySeenStore) Seen(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[key], nil
+}
+
+// MarkSeen implements SeenStore.
+func (s *MemorySeenStore) MarkSeen(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	s.seen[key] = true
+	return nil
+}
+
+// An Episode is a new episode of a watched program.
+type Episode struct {
+	Program   string // the program URL or media ID as given to the Watcher
+	Proxy     *BroadcastProxy
+	Broadcast *Broadcast // set if the Watcher resolves episodes
+}
+
+// programKey returns the key in a SeenStore that marks program as polled
+// before.
+func programKey(program string) string {
+	return "program:" + program
+}
+
+// episodeKey returns the key of bp in a SeenStore.
+func episodeKey(bp *BroadcastProxy) string {
+	if id := bp.ID(); id != "" {
+		return string(id)
+	}
+	return bp.URL
+}
+
+// Default Watcher settings.
+const (
+	DefaultWatchInterval = 15 * time.Minute
+	defaultMaxBackoffN   = 16 // times the interval
+)
+
+// A Watcher periodically gets the pages of a set of programs and reports the
+// episodes it has not seen before.
+//
+// The first time a program is polled, its episodes are marked as seen without
+// being reported, unless EmitExisting is set. This way, following a program
+// does not report its whole back catalog. That a program was polled before is
+// recorded in the store as well, with the key "program:" followed by the
+// program.
+type Watcher struct {
+	// Client is used to get the pages. If nil, DefaultClient is used.
+	Client *Client
+
+	// Programs are the URLs or media IDs of the watched programs.
+	Programs []string
+
+	// Interval is the time between polls of a program. If zero,
+	// DefaultWatchInterval is used. Up to Jitter is added at random to
+	// each interval, to spread the requests.
+	Interval time.Duration
+	Jitter   time.Duration
+
+	// MaxBackoff is the maximum time between polls of a program that fails.
+	// After each consecutive failure the interval is doubled, up to
+	// MaxBackoff. If zero, 16 times the interval is used.
+	MaxBackoff time.Duration
+
+	// Store remembers the seen episodes. If nil, they are kept in memory.
+	Store SeenStore
+
+	// Clock is used to wait between polls. If nil, the system clock is used.
+	Clock Clock
+
+	// Resolve makes the Watcher get the broadcast page of every new episode
+	// and report it in Episode.Broadcast.
+	Resolve bool
+
+	// EmitExisting reports the episodes listed when a program is first
+	// polled, instead of marking them as seen.
+	EmitExisting bool
+
+	// OnError, if set, is called with errors polling a program and with
+	// errors resolving an episode. An episode that cannot be resolved is
+	// skipped, and reported once it can be.
+	OnError func(program string, err error)
+
+	initOnce sync.Once
+	store    SeenStore
+}
+
+// watchState is the polling state of a program.
+type watchState struct {
+	program  string
+	next     time.Time
+	failures int
+}
+
+// Run polls the programs until ctx is done, calling fn with every new
+// episode, oldest first. An episode is marked as seen after fn returns. Run
+// returns the error of ctx.
+func (w *Watcher) Run(ctx context.Context, fn func(Episode)) error {
+	return w.run(ctx, deliverAll(fn))
+}
+
+// deliverAll returns a delivery function for poll that calls fn and always
+// reports the episode as delivered.
+func deliverAll(fn func(Episode)) func(Episode) bool {
+	return func(e Episode) bool {
+		fn(e)
+		return true
+	}
+}
+
+// run is Run with a delivery function that reports whether the episode was
+// delivered. Episodes that are not delivered are not marked as seen.
+func (w *Watcher) run(ctx context.Context, deliver func(Episode) bool) error {
+	w.init()
+
+	clock := w.clock()
+	now := clock.Now()
+	states := make([]*watchState, len(w.Programs))
+	for i, p := range w.Programs {
+		states[i] = &watchState{program: p, next: now}
+	}
+
+	for {
+		if len(states) == 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		next := states[0].next
+		for _, s := range states[1:] {
+			if s.next.Before(next) {
+				next = s.next
+			}
+		}
+
+		if d := next.Sub(clock.Now()); d > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-clock.After(d):
+			}
+		}
+
+		for _, s := range states {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if s.next.After(clock.Now()) {
+				continue
+			}
+
+			err := w.poll(ctx, s.program, deliver)
+			if err != nil && ctx.Err() == nil && w.OnError != nil {
+				w.OnError(s.program, err)
+			}
+			s.next = clock.Now().Add(w.delay(s, err))
+		}
+	}
+}
+
+// Watch polls the programs until ctx is done, like Run, and sends new
+// episodes on the returned channel. An episode is marked as seen once it is
+// received; an episode that is not received before ctx is done is reported
+// again by the next Watcher using the same store. The channel is closed when
+// ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan Episode {
+	ch := make(chan Episode)
+	go func() {
+		defer close(ch)
+		w.run(ctx, func(e Episode) bool {
+			select {
+			case ch <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+// Poll gets the page of program once and calls fn with every new episode,
+// oldest first.
+func (w *Watcher) Poll(ctx context.Context, program string, fn func(Episode)) error {
+	w.init()
+	return w.poll(ctx, program, deliverAll(fn))
+}
+
+func (w *Watcher) poll(ctx context.Context, program string, deliver func(Episode) bool) error {
+	c := w.Client
+	if c == nil {
+		c = DefaultClient
+	}
+
+	p, err := c.GetProgram(ctx, program)
+	if err != nil {
+		return err
+	}
+
+	polled, err := w.store.Seen(programKey(program))
+	if err != nil {
+		return err
+	}
+
+	var bps []*BroadcastProxy
+	for _, bp := range p.bs {
+		seen, err := w.store.Seen(episodeKey(bp))
+		if err != nil {
+			return err
+		}
+		if !seen {
+			bps = append(bps, bp)
+		}
+	}
+
+	// Report the oldest episodes first, as the page lists the newest first.
+	sort.SliceStable(bps, func(i, j int) bool {
+		return bps[i].Date.Before(bps[j].Date)
+	})
+
+	emit := polled || w.EmitExisting
+	for _, bp := range bps {
+		if emit {
+			e := Episode{Program: program, Proxy: bp}
+			if w.Resolve {
+				b, err := c.Resolve(ctx, bp)
+				if b == nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					// An episode that cannot be resolved does not hold
+					// up the newer ones. It is tried again next poll.
+					if w.OnError != nil {
+						w.OnError(program, err)
+					}
+					continue
+				}
+				e.Broadcast = b
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !deliver(e) {
+				return ctx.Err()
+			}
+		}
+
+		if err := w.store.MarkSeen(episodeKey(bp)); err != nil {
+			return err
+		}
+	}
+
+	if !polled {
+		return w.store.MarkSeen(programKey(program))
+	}
+	return nil
+}
+
+func (w *Watcher) init() {
+	w.initOnce.Do(func() {
+		w.store = w.Store
+		if w.store == nil {
+			w.store = &MemorySeenStore{}
+		}
+	})
+}
+
+func (w *Watcher) clock() Clock {
+	if w.Clock != nil {
+		return w.Clock
+	}
+	return systemClock{}
+}
+
+// delay returns the time until the next poll of s, given the error of the
+// last poll.
+func (w *Watcher) delay(s *watchState, err error) time.Duration {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	if err != nil {
+		s.failures++
+	} else {
+		s.failures = 0
+	}
+
+	d := interval
+	if s.failures > 0 {
+		max := w.MaxBackoff
+		if max <= 0 {
+			max = defaultMaxBackoffN * interval
+		}
+		for i := 0; i < s.failures && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+	}
+
+	if w.Jitter > 0 {
+		d += rand.N(w.Jitter)
+	}
+
+	return d
+}
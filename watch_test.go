@@ -0,0 +1,345 @@
+package gemist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProgramPage returns a program page listing episodes eps, newest first.
+// Episode k is broadcast on October k, 2007.
+func testProgramPage(eps ...int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head>
+<meta content="Radio Bergeijk" name="og:title" />
+<meta content="Radio Bergeijk" name="og:description" />
+<meta content="http://www.npo.nl/radio-bergeijk/POMS_S_VPRO_396280" name="og:url" />
+<meta content="http://images.poms.omroep.nl/image/1.jpg" name="og:image" />
+</head><body><div id='broadcasts-block'><div class='content'>`)
+	fmt.Fprintf(&b, "<div class='search-results' data-num-found='%d'>", len(eps))
+	for _, k := range eps {
+		fmt.Fprintf(&b, `<div class='list-item'>
+<div class='span4'><div class='image-container'>
+<a href="/radio-bergeijk/%02[1]d-10-2007/POMS_VPRO_%[2]d"><img src="http://images.poms.omroep.nl/image/2.png" />
+<div class="overlay-icon">25:02</div>
+</a></div></div>
+<div class='span8'>
+<a href="/radio-bergeijk/%02[1]d-10-2007/POMS_VPRO_%[2]d"><h4>Aflevering %[1]d</h4>
+<h5>Radio 1 · Za %[1]d okt 2007 18:32 · 25 min</h5>
+</a></div>
+</div>
+`, k, 1000+k)
+	}
+	b.WriteString("</div></div></div></body></html>")
+	return b.String()
+}
+
+// programServer serves a program page whose episodes and status can be
+// changed while it runs.
+type programServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	eps    []int
+	status int
+}
+
+func newProgramServer(eps ...int) *programServer {
+	s := &programServer{eps: eps}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+			return
+		}
+		fmt.Fprint(w, testProgramPage(s.eps...))
+	}))
+	return s
+}
+
+func (s *programServer) set(status int, eps ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.eps = status, eps
+}
+
+type fakeWait struct {
+	d  time.Duration
+	ch chan time.Time
+}
+
+// fakeClock is a Clock whose waits are reported on a channel and only end
+// when the test advances the clock.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits chan fakeWait
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+		waits: make(chan fakeWait, 10),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	w := fakeWait{d, make(chan time.Time, 1)}
+	c.waits <- w
+	return w.ch
+}
+
+// wait waits for the watcher to wait on the clock.
+func (c *fakeClock) wait(t *testing.T) fakeWait {
+	select {
+	case w := <-c.waits:
+		return w
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not wait")
+		return fakeWait{}
+	}
+}
+
+// advance advances the clock past w, ending it.
+func (c *fakeClock) advance(w fakeWait) {
+	c.mu.Lock()
+	c.now = c.now.Add(w.d)
+	now := c.now
+	c.mu.Unlock()
+	w.ch <- now
+}
+
+func episodeIDs(es []Episode) []string {
+	var ids []string
+	for _, e := range es {
+		ids = append(ids, string(e.Proxy.ID()))
+	}
+	return ids
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	s := newProgramServer(3, 2, 1)
+	defer s.Close()
+
+	w := &Watcher{Client: &Client{BaseURL: s.URL}}
+
+	var got []Episode
+	collect := func(e Episode) { got = append(got, e) }
+
+	// Existing episodes are not reported.
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Empty(t, got)
+
+	s.set(0, 5, 4, 3, 2, 1)
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Equal(t, []string{"POMS_VPRO_1004", "POMS_VPRO_1005"}, episodeIDs(got))
+	assert.Equal(t, "POMS_S_VPRO_396280", got[0].Program)
+	assert.Nil(t, got[0].Broadcast)
+
+	got = nil
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Empty(t, got)
+}
+
+func TestWatcher_Poll_backlog(t *testing.T) {
+	s := newProgramServer(3, 2, 1)
+	defer s.Close()
+
+	store := &MemorySeenStore{}
+	w := &Watcher{Client: &Client{BaseURL: s.URL}, Store: store}
+
+	var got []Episode
+	collect := func(e Episode) { got = append(got, e) }
+
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Empty(t, got)
+
+	polled, err := store.Seen("program:POMS_S_VPRO_396280")
+	require.NoError(t, err)
+	assert.True(t, polled, "program not marked as polled")
+
+	// More new episodes than the page lists, so none of the listed episodes
+	// was seen before.
+	s.set(0, 9, 8, 7, 6, 5)
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Equal(t, []string{"POMS_VPRO_1005", "POMS_VPRO_1006", "POMS_VPRO_1007", "POMS_VPRO_1008", "POMS_VPRO_1009"}, episodeIDs(got))
+}
+
+func TestWatcher_poll_notDelivered(t *testing.T) {
+	s := newProgramServer(1)
+	defer s.Close()
+
+	store := &MemorySeenStore{}
+	w := &Watcher{Client: &Client{BaseURL: s.URL}, Store: store}
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", func(Episode) {}))
+
+	s.set(0, 3, 2, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	err := w.poll(ctx, "POMS_S_VPRO_396280", func(e Episode) bool {
+		got = append(got, string(e.Proxy.ID()))
+		cancel()
+		return false
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"POMS_VPRO_1002"}, got)
+
+	for _, id := range []string{"POMS_VPRO_1002", "POMS_VPRO_1003"} {
+		seen, err := store.Seen(id)
+		require.NoError(t, err)
+		assert.False(t, seen, "%s marked as seen", id)
+	}
+
+	var all []Episode
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", func(e Episode) { all = append(all, e) }))
+	assert.Equal(t, []string{"POMS_VPRO_1002", "POMS_VPRO_1003"}, episodeIDs(all))
+}
+
+func TestWatcher_Poll_emitExisting(t *testing.T) {
+	s := newProgramServer(2, 1)
+	defer s.Close()
+
+	store := &MemorySeenStore{}
+	w := &Watcher{Client: &Client{BaseURL: s.URL}, Store: store, EmitExisting: true}
+
+	var got []Episode
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", func(e Episode) {
+		got = append(got, e)
+	}))
+	assert.Equal(t, []string{"POMS_VPRO_1001", "POMS_VPRO_1002"}, episodeIDs(got))
+
+	seen, err := store.Seen("POMS_VPRO_1002")
+	assert.NoError(t, err)
+	assert.True(t, seen)
+}
+
+func TestWatcher_Poll_resolveError(t *testing.T) {
+	eps := []int{1}
+	broken := true
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "POMS_VPRO_1002") && broken:
+			http.NotFound(w, r)
+		case strings.Contains(r.URL.Path, "POMS_VPRO_"):
+			fmt.Fprint(w, testDataBroadcastAudio)
+		default:
+			fmt.Fprint(w, testProgramPage(eps...))
+		}
+	}))
+	defer s.Close()
+
+	var errs []error
+	w := &Watcher{
+		Client:  &Client{BaseURL: s.URL},
+		Resolve: true,
+		OnError: func(program string, err error) { errs = append(errs, err) },
+	}
+
+	var got []Episode
+	collect := func(e Episode) { got = append(got, e) }
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+
+	// The broken episode is skipped, and the newer one still reported.
+	eps = []int{3, 2, 1}
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Equal(t, []string{"POMS_VPRO_1003"}, episodeIDs(got))
+	if assert.Len(t, errs, 1) {
+		assert.ErrorIs(t, errs[0], ErrNotFound)
+	}
+
+	// It is reported once it can be resolved.
+	got, broken = nil, false
+	require.NoError(t, w.Poll(context.Background(), "POMS_S_VPRO_396280", collect))
+	assert.Equal(t, []string{"POMS_VPRO_1002"}, episodeIDs(got))
+}
+
+func TestWatcher_Run(t *testing.T) {
+	s := newProgramServer(1)
+	defer s.Close()
+
+	clock := newFakeClock()
+	var errs []error
+	w := &Watcher{
+		Client:   &Client{BaseURL: s.URL},
+		Programs: []string{"POMS_S_VPRO_396280"},
+		Interval: time.Hour,
+		Clock:    clock,
+		OnError:  func(program string, err error) { errs = append(errs, err) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := w.Watch(ctx)
+
+	// The first poll is immediate and sets the baseline.
+	wt := clock.wait(t)
+	assert.Equal(t, time.Hour, wt.d)
+	s.set(0, 2, 1)
+	clock.advance(wt)
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, MediaID("POMS_VPRO_1002"), e.Proxy.ID())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no episode")
+	}
+
+	// Errors back off exponentially.
+	wt = clock.wait(t)
+	assert.Equal(t, time.Hour, wt.d)
+	s.set(http.StatusInternalServerError)
+	clock.advance(wt)
+
+	for _, d := range []time.Duration{2 * time.Hour, 4 * time.Hour, 8 * time.Hour} {
+		wt = clock.wait(t)
+		assert.Equal(t, d, wt.d)
+		if d == 8*time.Hour {
+			s.set(0, 2, 1)
+		}
+		clock.advance(wt)
+	}
+
+	wt = clock.wait(t)
+	assert.Equal(t, time.Hour, wt.d)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	require.Len(t, errs, 3)
+	var herr *HTTPError
+	assert.True(t, errors.As(errs[0], &herr))
+}
+
+func TestWatcher_delay(t *testing.T) {
+	w := &Watcher{Interval: time.Minute, MaxBackoff: 5 * time.Minute}
+	s := &watchState{}
+
+	assert.Equal(t, time.Minute, w.delay(s, nil))
+	assert.Equal(t, 2*time.Minute, w.delay(s, ErrNotFound))
+	assert.Equal(t, 4*time.Minute, w.delay(s, ErrNotFound))
+	assert.Equal(t, 5*time.Minute, w.delay(s, ErrNotFound))
+	assert.Equal(t, 5*time.Minute, w.delay(s, ErrNotFound))
+	assert.Equal(t, time.Minute, w.delay(s, nil))
+
+	w = &Watcher{Jitter: time.Second}
+	for i := 0; i < 10; i++ {
+		d := w.delay(s, nil)
+		assert.True(t, d >= DefaultWatchInterval && d < DefaultWatchInterval+time.Second)
+	}
+}